@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// checksumSuffix 是 release 中校验文件相对资源文件的后缀
+const checksumSuffix = ".sha256"
+
+// sidecarAsset 判断 name 是否为其他资源的校验文件或分离签名, 这类文件不参与资源匹配
+func sidecarAsset(name string) bool {
+	return strings.HasSuffix(name, checksumSuffix) ||
+		slices.ContainsFunc(signatureSuffixes, func(s string) bool { return strings.HasSuffix(name, s) })
+}
+
+// 资源匹配字段
+const (
+	matchName  = "name"  // 按文件名匹配
+	matchLabel = "label" // 按 release 中设置的显示名称匹配
+)
+
+// selectAssets 返回 field 字段匹配任一 patterns (支持 * ? [] 通配) 的资源, 校验文件和签名本身不参与匹配
+// 逐一输出每个模式找到的资源, 存在未匹配任何资源的模式时返回错误
+func selectAssets(release *Release, field string, patterns []string) ([]*ReleaseAsset, error) {
+	var (
+		assets  []*ReleaseAsset
+		missing []string
+	)
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf(tr("无效的资源匹配模式 %q: %w"), pattern, err)
+		}
+		var found []string
+		for i := range release.Assets {
+			asset := &release.Assets[i]
+			if sidecarAsset(asset.Name) {
+				continue
+			}
+			if ok, _ := path.Match(pattern, asset.field(field)); !ok {
+				continue
+			}
+			found = append(found, asset.Name)
+			if !slices.Contains(assets, asset) {
+				assets = append(assets, asset)
+			}
+		}
+		if len(found) == 0 {
+			log.Printf(tr("[缺失] %s"), pattern)
+			missing = append(missing, pattern)
+		} else {
+			log.Printf(tr("[找到] %s: %s"), pattern, strings.Join(found, ", "))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, withKind(ErrAssetNotFound, fmt.Errorf(tr("未找到 %s 匹配 %s 的资源"), field, strings.Join(missing, ", ")))
+	}
+	return assets, nil
+}
+
+// selectAssetRegex 返回 field 字段匹配正则 re 的资源, 校验文件和签名本身不参与匹配
+// 多个资源匹配时取 updated_at 最新的一个, 相同时取名称按字典序最小的一个, 保证每次选择一致
+func selectAssetRegex(release *Release, field string, re *regexp.Regexp) (*ReleaseAsset, error) {
+	var found []*ReleaseAsset
+	for i := range release.Assets {
+		asset := &release.Assets[i]
+		if !sidecarAsset(asset.Name) && re.MatchString(asset.field(field)) {
+			found = append(found, asset)
+		}
+	}
+	if len(found) == 0 {
+		log.Printf(tr("[缺失] %s"), re)
+		return nil, withKind(ErrAssetNotFound, fmt.Errorf(tr("未找到 %s 匹配正则 %s 的资源"), field, re))
+	}
+	slices.SortFunc(found, func(a, b *ReleaseAsset) int {
+		if c := b.UpdatedAt.Compare(a.UpdatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	log.Printf(tr("[找到] %s: %s"), re, found[0].Name)
+	if len(found) > 1 {
+		var others []string
+		for _, a := range found[1:] {
+			others = append(others, a.Name)
+		}
+		log.Printf(tr("正则 %s 匹配到多个资源, 已选择 %s, 忽略 %s"), re, found[0].Name, strings.Join(others, ", "))
+	}
+	return found[0], nil
+}
+
+// backendAssets 按 -asset-regex 或 -asset 选择要下载的后端资源
+// 指定了 -include-sourcemap 且 release 中有对应的 source map 时一并下载
+func backendAssets(cfg *Config, release *Release) ([]*ReleaseAsset, error) {
+	var assets []*ReleaseAsset
+	if cfg.assetRegex == nil {
+		var err error
+		if assets, err = selectAssets(release, cfg.MatchField, cfg.Assets); err != nil {
+			return nil, err
+		}
+	} else {
+		asset, err := selectAssetRegex(release, cfg.MatchField, cfg.assetRegex)
+		if err != nil {
+			return nil, err
+		}
+		assets = []*ReleaseAsset{asset}
+	}
+	if cfg.IncludeSourcemap {
+		if m := findAsset(release, cfg.SourcemapName); m == nil {
+			log.Printf(tr("release %s 中没有 source map %s, 只更新后端文件"), release.TagName, cfg.SourcemapName)
+		} else if !slices.Contains(assets, m) {
+			assets = append(assets, m)
+		}
+	}
+	return assets, nil
+}
+
+// waitAssetInterval 为 -wait-asset 等待期间重新获取 release 的间隔
+const waitAssetInterval = 30 * time.Second
+
+// waitForAsset 用 find 在 release 中查找所需资源
+// 资源缺失且指定了 -wait-asset 时, 按间隔重新获取同一 tag 的 release, 直到资源出现或超时
+// 返回值为最后一次获取到的 release
+func waitForAsset(cfg *Config, repo string, release *Release, find func(*Release) error) (*Release, error) {
+	err := find(release)
+	if err == nil || cfg.WaitAsset <= 0 || !errors.Is(err, ErrAssetNotFound) {
+		return release, err
+	}
+	deadline := time.Now().Add(cfg.WaitAsset)
+	for time.Now().Before(deadline) {
+		wait := min(waitAssetInterval, time.Until(deadline))
+		log.Printf(tr("%s 的资源尚未全部上传, %s 后重新检查 (最长等待至 %s)"), release.TagName, wait.Round(time.Second), deadline.Local().Format(time.DateTime))
+		time.Sleep(wait)
+
+		latest, fetchErr := releaseByTag(cfg, repo, release.TagName)
+		if fetchErr != nil {
+			return release, fetchErr
+		}
+		release = latest
+		if err = find(release); err == nil {
+			storeRelease(cfg, repo, release)
+			log.Printf(tr("%s 的资源已上传"), release.TagName)
+			return release, nil
+		}
+	}
+	return release, fmt.Errorf(tr("等待 %s 上传资源超时 (-wait-asset %s): %w"), release.TagName, cfg.WaitAsset, err)
+}
+
+// signatureSuffixes 为 release 中分离签名文件相对资源文件的后缀, 按顺序查找
+var signatureSuffixes = []string{".asc", ".sig"}
+
+// verifyAssetSignature 指定了 -gpg-key 时下载资源的分离签名并用其中的公钥校验 data
+// 启用校验后 release 中没有签名文件同样视为失败, 避免签名被移除后静默跳过校验
+func verifyAssetSignature(cfg *Config, release *Release, asset *ReleaseAsset, data []byte) error {
+	if len(cfg.pgpKeys) == 0 {
+		return nil
+	}
+	var sigAsset *ReleaseAsset
+	for _, suffix := range signatureSuffixes {
+		if sigAsset = findAsset(release, asset.Name+suffix); sigAsset != nil {
+			break
+		}
+	}
+	if sigAsset == nil {
+		return withKind(ErrDownloadFailed, fmt.Errorf(tr("已指定 -gpg-key, 但 release %s 中没有 %s 的签名文件 (.asc / .sig)"), release.TagName, asset.Name))
+	}
+	sig, _, err := downloadAsset(cfg, tr("签名"), sigAsset, nil)
+	if err != nil {
+		return err
+	}
+	if err := verifyPGPSignature(cfg.pgpKeys, data, sig); err != nil {
+		return withKind(ErrDownloadFailed, fmt.Errorf(tr("%s 的 GPG 签名校验失败: %w"), asset.Name, err))
+	}
+	log.Printf(tr("%s 的 GPG 签名校验通过"), asset.Name)
+	return nil
+}
+
+// assetData 是单个资源的下载结果
+type assetData struct {
+	asset  *ReleaseAsset
+	data   []byte
+	source downloadSource
+}
+
+// downloadAssets 以有限的并发数下载多个资源并逐一校验,
+// 任一资源失败时汇总所有错误返回, 不会只丢弃其中一部分
+func downloadAssets(cfg *Config, name string, release *Release, assets []*ReleaseAsset) ([]assetData, error) {
+	results := make([]assetData, len(assets))
+	errs := make([]error, len(assets))
+	sem := make(chan struct{}, max(cfg.DownloadWorkers, 1))
+
+	var wg sync.WaitGroup
+	for i, asset := range assets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			expected, err := expectedChecksum(cfg, release, asset)
+			var (
+				data   []byte
+				source downloadSource
+			)
+			if err == nil {
+				data, source, err = downloadAsset(cfg, name, asset, expected)
+			}
+			if err == nil {
+				err = verifyAssetSignature(cfg, release, asset, data)
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", asset.Name, err)
+				return
+			}
+			results[i] = assetData{asset: asset, data: data, source: source}
+		}()
+	}
+	wg.Wait()
+
+	for i, asset := range assets {
+		if errs[i] != nil {
+			log.Printf(tr("[失败] %s: %v"), asset.Name, errs[i])
+		} else {
+			log.Printf(tr("[成功] %s (%s)"), asset.Name, formatSize(int64(len(results[i].data))))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// expectedChecksum 返回资源内容应有的 sha256: API 声明了摘要时以其为准, 不再查找校验文件;
+// 否则使用 release 中同名的 .sha256 校验文件, 都没有时返回 nil, 表示不校验
+func expectedChecksum(cfg *Config, release *Release, asset *ReleaseAsset) ([]byte, error) {
+	if digest := assetDigest(asset); digest != "" {
+		sum, err := hex.DecodeString(digest)
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf(tr("%s 的 API 摘要格式无效: %q"), asset.Name, asset.Digest)
+		}
+		if cfg.Verbose {
+			if release.Immutable {
+				log.Printf(tr("使用 API 声明的 sha256 校验 %s (不可变 release)"), asset.Name)
+			} else {
+				log.Printf(tr("使用 API 声明的 sha256 校验 %s"), asset.Name)
+			}
+		}
+		return sum, nil
+	}
+	sumAsset := findAsset(release, asset.Name+checksumSuffix)
+	if sumAsset == nil {
+		return nil, nil
+	}
+	sumData, _, err := downloadAsset(cfg, tr("校验"), sumAsset, nil)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(sumData))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf(tr("校验文件 %s 内容为空"), sumAsset.Name)
+	}
+	// 长度不对 (如截断或其他算法) 的校验和永远无法匹配, 不应进入下载重试和镜像切换
+	expected, err := hex.DecodeString(fields[0])
+	if err != nil {
+		return nil, permanent(fmt.Errorf(tr("校验文件 %s 格式无效: %w"), sumAsset.Name, err))
+	}
+	if len(expected) != sha256.Size {
+		return nil, permanent(fmt.Errorf(tr("校验文件 %s 格式无效: %q 不是 sha256 校验和"), sumAsset.Name, fields[0]))
+	}
+	return expected, nil
+}
+
+// inputDecoders 按扩展名 (不含点) 列出可识别的上游压缩格式
+var inputDecoders = map[string]func(io.Reader) (io.ReadCloser, error){
+	"gz": func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	"bz2": func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	},
+	"zst": func(r io.Reader) (io.ReadCloser, error) {
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	},
+}
+
+// decompressInput 若资源以 enabled 中的压缩格式发布, 解压后返回原始数据及去掉扩展名的文件名,
+// 保证后续流程总是处理原始 JS; 解压后超过 limit 字节 (limit > 0 时) 时返回错误
+func decompressInput(name string, data []byte, enabled []string, limit int64) (string, []byte, error) {
+	for _, ext := range enabled {
+		base, ok := strings.CutSuffix(name, "."+ext)
+		if !ok {
+			continue
+		}
+		r, err := inputDecoders[ext](bytes.NewReader(data))
+		if err != nil {
+			return "", nil, fmt.Errorf(tr("解压 %s 失败: %w"), name, err)
+		}
+		raw, err := readLimited(r, limit)
+		r.Close()
+		if err != nil {
+			return "", nil, fmt.Errorf(tr("解压 %s 失败: %w"), name, err)
+		}
+		log.Printf(tr("%s 为 %s 压缩格式, 解压后大小: %s"), name, ext, formatSize(int64(len(raw))))
+		return base, raw, nil
+	}
+	return name, data, nil
+}
+
+// readLimited 读取 r 的全部内容, 超过 limit 字节 (limit > 0 时) 时中止并返回错误
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf(tr("解压后的内容超过上限 %s"), formatSize(limit))
+	}
+	return data, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestDecompressInputLimit(t *testing.T) {
+	raw := []byte(strings.Repeat("a", 4096))
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(raw)
+	w.Close()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zst := enc.EncodeAll(raw, nil)
+	enc.Close()
+
+	for name, data := range map[string][]byte{"sub-store.bundle.js.gz": gz.Bytes(), "sub-store.bundle.js.zst": zst} {
+		enabled := []string{"gz", "zst"}
+		base, got, err := decompressInput(name, data, enabled, int64(len(raw)))
+		if err != nil {
+			t.Fatalf("decompressInput(%s): %v", name, err)
+		}
+		if base != "sub-store.bundle.js" || !bytes.Equal(got, raw) {
+			t.Errorf("decompressInput(%s) = %s, %d 字节", name, base, len(got))
+		}
+		if _, got, err = decompressInput(name, data, enabled, 0); err != nil || !bytes.Equal(got, raw) {
+			t.Errorf("limit 为 0 时不应限制大小: %v", err)
+		}
+		if _, _, err := decompressInput(name, data, enabled, int64(len(raw))-1); err == nil {
+			t.Errorf("decompressInput(%s) 解压后超过上限时应返回错误", name)
+		}
+	}
+
+	if base, got, err := decompressInput("sub-store.bundle.js", raw, []string{"gz"}, 1); err != nil || base != "sub-store.bundle.js" || !bytes.Equal(got, raw) {
+		t.Errorf("未压缩的资源应原样返回: %s, %v", base, err)
+	}
+}
+
+func TestSelectAssetsSkipsSidecars(t *testing.T) {
+	release := &Release{Assets: []ReleaseAsset{
+		{Name: "sub-store.bundle.js"},
+		{Name: "sub-store.bundle.js.sha256"},
+		{Name: "sub-store.bundle.js.asc"},
+		{Name: "sub-store.bundle.js.sig"},
+	}}
+	assets, err := selectAssets(release, matchName, []string{"sub-store.bundle.js*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 1 || assets[0].Name != "sub-store.bundle.js" {
+		var names []string
+		for _, a := range assets {
+			names = append(names, a.Name)
+		}
+		t.Errorf("selectAssets = %v, want [sub-store.bundle.js]", names)
+	}
+
+	asset, err := selectAssetRegex(release, matchName, regexp.MustCompile(`^sub-store\.bundle\.js`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asset.Name != "sub-store.bundle.js" {
+		t.Errorf("selectAssetRegex = %s, want sub-store.bundle.js", asset.Name)
+	}
+
+	if _, err := selectAssets(release, matchName, []string{"*.asc"}); err == nil {
+		t.Error("只匹配到签名文件时应报告缺失")
+	}
+}
+
+func TestExpectedChecksumFile(t *testing.T) {
+	sum := sha256.Sum256([]byte("bundle"))
+	valid := hex.EncodeToString(sum[:])
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	asset := ReleaseAsset{Name: "sub-store.bundle.js"}
+	release := &Release{Assets: []ReleaseAsset{asset, {Name: "sub-store.bundle.js.sha256", BrowserDownloadURL: srv.URL + "/sum"}}}
+	cfg := &Config{Retry: RetryPolicy{Attempts: 1}}
+
+	body = valid + "  sub-store.bundle.js\n"
+	got, err := expectedChecksum(cfg, release, &asset)
+	if err != nil || !bytes.Equal(got, sum[:]) {
+		t.Fatalf("expectedChecksum = %x, %v, want %x", got, err, sum)
+	}
+
+	for _, bad := range []string{valid[:40], valid + "00", "not-hex"} {
+		body = bad + "  sub-store.bundle.js\n"
+		_, err := expectedChecksum(cfg, release, &asset)
+		var perm permanentError
+		if err == nil || !errors.As(err, &perm) {
+			t.Errorf("校验文件内容为 %q 时应返回不可重试的错误, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// assetDigest 返回 API 声明的资源 sha256 (十六进制), 未声明时返回空字符串
+func assetDigest(asset *ReleaseAsset) string {
+	hexSum, ok := strings.CutPrefix(asset.Digest, "sha256:")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(hexSum)
+}
+
+// cachedDownload 按 API 声明的 sha256 在下载缓存目录中查找资源内容
+func cachedDownload(cfg *Config, asset *ReleaseAsset) []byte {
+	return cachedBlob(cfg, assetDigest(asset))
+}
+
+// cachedBlob 按 sha256 在下载缓存目录中查找内容, 命中时刷新其修改时间
+func cachedBlob(cfg *Config, digest string) []byte {
+	if cfg.DownloadCache == "" || digest == "" {
+		return nil
+	}
+	p := filepath.Join(cfg.DownloadCache, digest)
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != digest {
+		log.Printf(tr("下载缓存 %s 内容与摘要不符, 已删除"), p)
+		os.Remove(p)
+		return nil
+	}
+	now := time.Now()
+	os.Chtimes(p, now, now)
+	return data
+}
+
+// storeDownload 以内容的 sha256 为文件名写入下载缓存, 并按 -download-cache-max-mb 清理
+func storeDownload(cfg *Config, data []byte) {
+	if cfg.DownloadCache == "" {
+		return
+	}
+	if err := os.MkdirAll(cfg.DownloadCache, 0755); err != nil {
+		log.Printf(tr("写入下载缓存失败: %v"), err)
+		return
+	}
+	sum := sha256.Sum256(data)
+	p := filepath.Join(cfg.DownloadCache, hex.EncodeToString(sum[:]))
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		log.Printf(tr("写入下载缓存失败: %v"), err)
+		return
+	}
+	evictDownloads(cfg.DownloadCache, cfg.CacheMaxMB<<20)
+}
+
+// evictDownloads 按最近使用时间从旧到新删除缓存文件, 直到总大小不超过 limit
+func evictDownloads(dir string, limit int64) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var (
+		files []os.FileInfo
+		total int64
+	)
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() || e.Name() == validatorsFile {
+			continue
+		}
+		files = append(files, info)
+		total += info.Size()
+	}
+	slices.SortFunc(files, func(a, b os.FileInfo) int { return a.ModTime().Compare(b.ModTime()) })
+	for _, f := range files {
+		if total <= limit {
+			break
+		}
+		if err := os.Remove(filepath.Join(dir, f.Name())); err == nil {
+			total -= f.Size()
+			log.Printf(tr("已从下载缓存中清除 %s (%s)"), f.Name(), formatSize(f.Size()))
+		}
+	}
+}
+
+// validatorsFile 为下载缓存目录中记录条件请求信息的文件
+const validatorsFile = "validators.json"
+
+// errNotModified 表示条件请求返回 304, 可继续使用缓存的内容
+var errNotModified = errors.New("not modified")
+
+// validators 为上次下载某资源时服务器返回的缓存校验信息
+type validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	SHA256       string `json:"sha256"`
+}
+
+// loadValidators 读取下载缓存目录中的条件请求信息, 以资源下载地址为键
+func loadValidators(cfg *Config) map[string]validators {
+	m := make(map[string]validators)
+	data, err := os.ReadFile(filepath.Join(cfg.DownloadCache, validatorsFile))
+	if err == nil {
+		json.Unmarshal(data, &m)
+	}
+	return m
+}
+
+// conditionalDownload 返回资源的条件请求信息及对应的缓存内容
+// 未启用下载缓存或缓存内容已被清除时返回空的校验信息, 此时不会发送条件头
+func conditionalDownload(cfg *Config, asset *ReleaseAsset) (*validators, []byte) {
+	if cfg.DownloadCache == "" {
+		return nil, nil
+	}
+	v := loadValidators(cfg)[asset.BrowserDownloadURL]
+	cached := cachedBlob(cfg, v.SHA256)
+	if cached == nil {
+		return &validators{}, nil
+	}
+	return &v, cached
+}
+
+// storeValidators 记录本次下载得到的 ETag / Last-Modified 及内容摘要
+func storeValidators(cfg *Config, asset *ReleaseAsset, v *validators, data []byte) {
+	if v == nil || (v.ETag == "" && v.LastModified == "") {
+		return
+	}
+	sum := sha256.Sum256(data)
+	v.SHA256 = hex.EncodeToString(sum[:])
+	m := loadValidators(cfg)
+	m[asset.BrowserDownloadURL] = *v
+	writeValidators(cfg, m)
+}
+
+// dropValidators 删除资源的条件请求信息及其对应的缓存内容, 下次下载时不再发送条件头
+func dropValidators(cfg *Config, asset *ReleaseAsset, v *validators) {
+	if v.SHA256 != "" {
+		os.Remove(filepath.Join(cfg.DownloadCache, v.SHA256))
+	}
+	m := loadValidators(cfg)
+	delete(m, asset.BrowserDownloadURL)
+	writeValidators(cfg, m)
+}
+
+// writeValidators 将条件请求信息写回下载缓存目录
+func writeValidators(cfg *Config, m map[string]validators) {
+	out, err := json.MarshalIndent(m, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(cfg.DownloadCache, validatorsFile), append(out, '\n'), 0644)
+	}
+	if err != nil {
+		log.Printf(tr("写入下载缓存失败: %v"), err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadAssetStaleNotModified(t *testing.T) {
+	fresh := []byte("new bundle")
+	var conditional []bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := r.Header.Get("If-None-Match")
+		conditional = append(conditional, etag != "")
+		if etag != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v2"`)
+		w.Write(fresh)
+	}))
+	defer srv.Close()
+
+	cfg := &Config{DownloadCache: t.TempDir(), CacheMaxMB: 1, Retry: RetryPolicy{Attempts: 1}}
+	asset := &ReleaseAsset{Name: "sub-store.bundle.js", BrowserDownloadURL: srv.URL + "/sub-store.bundle.js"}
+	// 缓存中是旧内容, 服务器却对条件请求返回 304
+	stale := []byte("old bundle")
+	storeDownload(cfg, stale)
+	storeValidators(cfg, asset, &validators{ETag: `"v1"`}, stale)
+	staleSum := sha256.Sum256(stale)
+
+	expected := sha256.Sum256(fresh)
+	data, source, err := downloadAsset(cfg, "后端", asset, expected[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(fresh) || source.cached {
+		t.Errorf("downloadAsset = %q (cached=%v), want %q", data, source.cached, fresh)
+	}
+	if len(conditional) != 2 || !conditional[0] || conditional[1] {
+		t.Errorf("请求是否带条件头: %v, want [true false]", conditional)
+	}
+	if fileExists(filepath.Join(cfg.DownloadCache, hex.EncodeToString(staleSum[:]))) {
+		t.Error("与期望摘要不符的缓存内容应被删除")
+	}
+	if v := loadValidators(cfg)[asset.BrowserDownloadURL]; v.ETag != `"v2"` || v.SHA256 != hex.EncodeToString(expected[:]) {
+		t.Errorf("validators = %+v, 应记录重新下载的内容", v)
+	}
+
+	// 缓存与期望一致时直接使用 304
+	conditional = nil
+	data, source, err = downloadAsset(cfg, "后端", asset, expected[:])
+	if err != nil || string(data) != string(fresh) || !source.cached || len(conditional) != 1 {
+		t.Errorf("304 且缓存有效时应使用缓存: data=%q cached=%v requests=%v err=%v", data, source.cached, conditional, err)
+	}
+}
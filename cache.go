@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// releaseCacheEntry 为一次缓存的 latest release 响应
+type releaseCacheEntry struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Release   *Release  `json:"release"`
+}
+
+// releaseCachePath 返回 release 缓存文件路径, 与状态文件位于同一目录
+func releaseCachePath(cfg *Config) string {
+	return filepath.Join(filepath.Dir(cfg.StateFile), "update-sub-store.release-cache.json")
+}
+
+// loadReleaseCache 读取 release 缓存, 文件不存在或损坏时返回空缓存
+func loadReleaseCache(path string) map[string]releaseCacheEntry {
+	cache := make(map[string]releaseCacheEntry)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		log.Printf(tr("release 缓存已损坏, 将忽略: %v"), err)
+		return make(map[string]releaseCacheEntry)
+	}
+	return cache
+}
+
+// cachedRelease 返回未超过 ttl 的缓存 release, 没有可用缓存时返回 nil
+func cachedRelease(cfg *Config, repo string) *Release {
+	if cfg.NoCache || cfg.CacheTTL <= 0 {
+		return nil
+	}
+	entry, ok := loadReleaseCache(releaseCachePath(cfg))[repo]
+	if !ok || entry.Release == nil {
+		return nil
+	}
+	age := time.Since(entry.FetchedAt)
+	if age < 0 || age > cfg.CacheTTL {
+		return nil
+	}
+	log.Printf(tr("使用 %s 前缓存的 %s release 信息 (-no-cache 可跳过缓存)"), age.Round(time.Second), repo)
+	return entry.Release
+}
+
+// storeRelease 将 release 写入缓存, 检查模式下不写入
+func storeRelease(cfg *Config, repo string, release *Release) {
+	if cfg.NoCache || cfg.CacheTTL <= 0 || cfg.DryRun {
+		return
+	}
+	path := releaseCachePath(cfg)
+	cache := loadReleaseCache(path)
+	cache[repo] = releaseCacheEntry{FetchedAt: time.Now(), Release: release}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Printf(tr("写入 release 缓存失败: %v"), err)
+	}
+}
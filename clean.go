@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// generatedFiles 返回本工具在工作目录中写入、可重新生成的文件: 状态文件、release 缓存和解压前端用的临时目录
+// 指定 -clean-cache 时还包括下载缓存中由本工具写入的文件; 目录中的其他文件不会被删除
+func generatedFiles(cfg *Config) []string {
+	files := []string{
+		"dist_temp",
+		cfg.StateFile,
+		releaseCachePath(cfg),
+	}
+	if cfg.CleanCache && cfg.DownloadCache != "" {
+		files = append(files, downloadCacheFiles(cfg.DownloadCache)...)
+	}
+	return files
+}
+
+// downloadCacheFiles 返回下载缓存目录中的缓存内容 (以 sha256 命名) 和条件请求信息文件
+func downloadCacheFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if _, err := hex.DecodeString(name); (err == nil && len(name) == 2*sha256.Size) || name == validatorsFile {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	return files
+}
+
+// runClean 删除工作目录中生成的文件，不会触及目标仓库
+func runClean(cfg *Config, destDir string) int {
+	destAbs, _ := filepath.Abs(destDir)
+	removed := 0
+	for _, f := range generatedFiles(cfg) {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			continue
+		}
+		if isWithin(destAbs, abs) {
+			log.Printf(tr("跳过目标目录中的文件: %s"), abs)
+			continue
+		}
+		if _, err := os.Lstat(abs); err != nil {
+			continue
+		}
+		if err := os.RemoveAll(abs); err != nil {
+			log.Printf(tr("删除 %s 失败: %v"), abs, err)
+			return exitError
+		}
+		log.Println(tr("已删除:"), abs)
+		removed++
+	}
+	if cfg.CleanCache && cfg.DownloadCache != "" {
+		// 只在缓存目录已清空时删除目录本身
+		if err := os.Remove(cfg.DownloadCache); err == nil {
+			log.Println(tr("已删除:"), cfg.DownloadCache)
+		}
+	}
+	if removed == 0 {
+		log.Println(tr("没有需要清理的文件"))
+	}
+	return exitOK
+}
+
+// isWithin 判断 path 是否位于 dir 目录之内
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunClean(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	write := func(name string) string {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	blob := strings.Repeat("ab", 32)
+	generated := []string{
+		write("update-sub-store.state.json"),
+		write("update-sub-store.release-cache.json"),
+		write("dist_temp/dist/index.html"),
+	}
+	cached := []string{write("cache/" + blob), write("cache/" + validatorsFile)}
+	kept := []string{
+		write("sub-store.bundle.js"),
+		write("sub-store.bundle.js.zst"),
+		write("download.part"),
+		write("cache/notes.txt"),
+	}
+	cfg := &Config{StateFile: filepath.Join(dir, "update-sub-store.state.json"), DownloadCache: filepath.Join(dir, "cache")}
+	dest := filepath.Join(dir, "assets")
+
+	if code := runClean(cfg, dest); code != exitOK {
+		t.Fatalf("runClean = %d", code)
+	}
+	for _, p := range generated {
+		if fileExists(p) {
+			t.Errorf("%s 应被删除", p)
+		}
+	}
+	for _, p := range append(cached, kept...) {
+		if !fileExists(p) {
+			t.Errorf("未指定 -clean-cache 时 %s 不应被删除", p)
+		}
+	}
+
+	cfg.CleanCache = true
+	if code := runClean(cfg, dest); code != exitOK {
+		t.Fatalf("runClean = %d", code)
+	}
+	for _, p := range cached {
+		if fileExists(p) {
+			t.Errorf("-clean-cache 时 %s 应被删除", p)
+		}
+	}
+	for _, p := range kept {
+		if !fileExists(p) {
+			t.Errorf("%s 不是本工具写入的文件, 不应被删除", p)
+		}
+	}
+}
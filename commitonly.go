@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// runCommitOnly 重新提交上次运行已写入但提交失败的文件, 不重新下载
+func runCommitOnly(cfg *Config, st *State, gitDir string) int {
+	if len(st.Pending) == 0 {
+		log.Println(tr("没有待提交的文件"))
+		return exitOK
+	}
+	var errs []error
+	bare := isBareRepo(gitDir)
+	for _, component := range slices.Sorted(maps.Keys(st.Pending)) {
+		p := st.Pending[component]
+		rels := relPaths(gitDir, p.Paths...)
+		// 裸仓库没有工作区可供比较, 是否有改动由在工作树中提交时判断
+		out := "bare"
+		if !bare {
+			var err error
+			if out, err = runGit(gitDir, "git status", append([]string{"status", "--porcelain", "--"}, rels...)...); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if strings.TrimSpace(out) == "" {
+			log.Printf(tr("%s %s 的文件已无改动, 可能已手动提交, 清除待提交记录"), component, p.Tag)
+			delete(st.Pending, component)
+			if err := st.save(cfg.StateFile); err != nil {
+				log.Printf(tr("保存状态文件失败: %v"), err)
+			}
+			continue
+		}
+		log.Printf(tr("重新提交 %s %s: %s"), component, p.Tag, strings.Join(rels, ", "))
+		if _, err := commitPending(cfg, st, gitDir, component, p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		log.Println(err)
+		return exitCode(err)
+	}
+	return exitOK
+}
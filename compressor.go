@@ -0,0 +1,29 @@
+package main
+
+import "github.com/klauspost/compress/zstd"
+
+// Compressor 压缩后端文件, 可通过 Config.Compressor 替换默认的 zstd 实现
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Ext() string // 压缩后文件追加的扩展名, 如 ".zst"
+}
+
+// zstdCompressor 是默认的 Compressor, 使用 compressZstd
+type zstdCompressor struct {
+	level   zstd.EncoderLevel
+	threads int
+}
+
+func (z zstdCompressor) Compress(data []byte) ([]byte, error) {
+	return compressZstd(data, z.level, z.threads)
+}
+
+func (z zstdCompressor) Ext() string { return ".zst" }
+
+// compressor 返回配置的 Compressor, 未设置时按 -level 使用 zstd
+func (c *Config) compressor() Compressor {
+	if c.Compressor != nil {
+		return c.Compressor
+	}
+	return zstdCompressor{level: c.encoderLevel(), threads: c.CompressThreads}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// identityCompressor 原样返回输入, 用于测试 Config.Compressor 的替换
+type identityCompressor struct{ calls *int }
+
+func (c identityCompressor) Compress(data []byte) ([]byte, error) {
+	*c.calls++
+	return bytes.Clone(data), nil
+}
+
+func (identityCompressor) Ext() string { return ".raw" }
+
+func TestConfigCompressor(t *testing.T) {
+	raw := []byte("console.log('sub-store')")
+	downloads := []assetData{{asset: &ReleaseAsset{Name: "sub-store.bundle.js"}, data: raw}}
+	dest := t.TempDir()
+	calls := 0
+
+	tests := []struct {
+		name      string
+		format    string
+		custom    Compressor
+		wantFiles []string
+		wantCalls int
+	}{
+		{"默认 zstd", formatZst, nil, []string{"sub-store.bundle.js.zst"}, 0},
+		{"自定义", formatZst, identityCompressor{&calls}, []string{"sub-store.bundle.js.raw"}, 1},
+		{"自定义且保留原文件", formatBoth, identityCompressor{&calls}, []string{"sub-store.bundle.js", "sub-store.bundle.js.raw"}, 1},
+		{"不压缩时不调用", formatJS, identityCompressor{&calls}, []string{"sub-store.bundle.js"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			cfg := &Config{Format: tt.format, CompressPattern: "*.js", Compressor: tt.custom, Level: "default", CompressThreads: 1}
+			if tt.custom == nil {
+				if _, ok := cfg.compressor().(zstdCompressor); !ok {
+					t.Fatalf("未设置 Compressor 时应使用 zstd, got %T", cfg.compressor())
+				}
+			} else if cfg.compressor() != tt.custom {
+				t.Fatalf("compressor() 未返回设置的 Compressor")
+			}
+
+			a, err := backendArtifact(cfg, dest, "v1.0.0", downloads)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, f := range a.files {
+				names = append(names, filepath.Base(f.path))
+				if filepath.Ext(f.path) == ".zst" && !bytes.HasPrefix(f.data, zstdMagic) {
+					t.Errorf("%s 不是 zstd 数据", f.path)
+				}
+			}
+			if !slices.Equal(names, tt.wantFiles) {
+				t.Errorf("files = %v, want %v", names, tt.wantFiles)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Compress 调用了 %d 次, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
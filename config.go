@@ -0,0 +1,868 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/klauspost/compress/zstd"
+)
+
+// 进程退出码，供自动化脚本判断运行结果
+const (
+	exitOK               = 0 // 已是最新或更新成功
+	exitError            = 1 // 运行出错
+	exitUsage            = 2 // 参数错误
+	exitUpdateAvailable  = 3 // 检查模式下发现可用更新
+	exitAssetNotFound    = 4 // release 中未找到所需资源
+	exitDownloadFailed   = 5 // 下载资源失败
+	exitGit              = 6 // git 提交或推送失败
+	exitProxyUnavailable = 7 // 代理与直连均不可用
+	exitVerifyMismatch   = 8 // verify 发现已提交文件与上游不一致
+	exitNoUpdate         = 9 // 指定 -fail-on-no-update 时没有任何更新
+
+	exitInterrupted = 130 // 被 SIGINT / SIGTERM 中断, 与 shell 的惯例一致
+)
+
+// 后端文件输出格式
+const (
+	formatZst  = "zst"  // 仅提交 zstd 压缩后的 sub-store.bundle.js.zst
+	formatJS   = "js"   // 仅提交未压缩的 sub-store.bundle.js
+	formatBoth = "both" // 同时提交两者
+)
+
+// Config 汇总命令行参数
+type Config struct {
+	Command           string // 子命令: update / check / verify / commit-only / config / clean / list-proxies / self-update
+	ConfigFile        string
+	Push              bool
+	DryRun            bool
+	DryRunGit         bool
+	FailOnNoUpdate    bool
+	NoCommit          bool
+	NoCompress        bool
+	Format            string
+	DestDir           string
+	PathTemplate      string
+	RepoPath          string
+	Worktree          string
+	MetaStripPrefix   string
+	MetaPrefix        string
+	UploadURL         string
+	UploadToken       string
+	Token             string
+	StateFile         string
+	MinCommitInterval time.Duration
+	Watch             time.Duration
+	WatchJitter       time.Duration
+	WatchMaxFailures  int
+	Once              bool
+	MetricsAddr       string
+	Retry             RetryPolicy
+	Mirrors           []string
+	UserAgent         string
+	CACert            string
+	InsecureTLS       bool
+	ShowVersion       bool
+	SelfRepo          string
+	List              int
+	Proxy             string
+	Level             string
+	CompressThreads   int
+	Since             time.Time
+	Branch            string
+	CommitType        string
+	CommitScope       string
+	CheckoutBranch    bool
+	RetryPush         bool
+	VerifyPush        bool
+	Amend             bool
+	NoTrailers        bool
+	PushAttempts      int
+	AppID             string
+	AppKey            string
+	AppInstallation   string
+	Assets            []string
+	AssetRegex        string
+	Input             string
+	Tag               string
+	Output            string
+	CompressPattern   string
+	IncludeSourcemap  bool
+	SourcemapName     string
+	DownloadWorkers   int
+	MaxDownloadMB     int64
+	MaxAssetMB        int64
+	MaxRedirects      int
+	DownloadCache     string
+	CleanCache        bool
+	CacheMaxMB        int64
+	ForceProxyScan    bool
+	ProxyConfig       string
+	ProxyTestTargets  []testTarget
+	ProxyCandidates   []string
+	ProxyFile         string
+	ProxyTimeout      time.Duration
+	ProxyFor          string
+	ProxyAttempts     int
+	ProxySamples      int
+	ExcludeProxies    []string
+	ProxyAllow        []string
+	ProxyDeny         []string
+	Verbose           bool
+	Lang              string
+	HashAlgo          string
+	GPGKey            string
+	FileMode          string
+	InputCompressions []string
+	ManifestURL       string
+	MatchField        string
+	WaitAsset         time.Duration
+	CacheTTL          time.Duration
+	SyncMetadata      bool
+	SinceLastRun      bool
+	DiffLines         bool
+	MinFreeMB         int64
+	MaxSizeChange     float64
+	Force             bool
+	Report            string
+	DumpRelease       string
+	VerifyCommand     string
+	LogFile           string
+	LogMaxMB          int64
+	LogKeep           int
+	NoCache           bool
+
+	Compressor Compressor // 压缩后端文件的实现, 为空时按 -level 使用 zstd; 仅供代码中设置
+
+	manifest   map[string]string  // 运行时获取的版本清单
+	flags      *flag.FlagSet      // 解析参数使用的 FlagSet, 供 config 子命令输出生效配置
+	assetRegex *regexp.Regexp     // validate 编译的 -asset-regex
+	pathTmpl   *template.Template // validate 解析的 -path-template
+	fileProxy  []string           // validate 从 -proxy-file 读取的候选代理
+	pgpKeys    openpgp.EntityList // validate 从 -gpg-key 读取的公钥
+}
+
+const usageHeader = `用法:
+  update-sub-store [选项]         检查并更新 Sub-Store 后端与前端文件
+  update-sub-store check [选项]   仅检查是否有可用更新, 等同于 -dry-run
+  update-sub-store verify [选项]  按已提交的版本重新生成后端与前端文件, 校验是否与已提交的文件一致
+  update-sub-store commit-only [选项]
+                                只重新提交上次已写入但提交失败的文件, 不重新下载
+  update-sub-store config [选项]  输出合并配置文件、环境变量和参数后的生效配置 (JSON, 隐藏 token 与密码)
+  update-sub-store clean [选项]   删除工作目录中生成的文件 (状态文件、release 缓存等), -clean-cache 同时清理下载缓存
+  update-sub-store list-proxies [选项]
+                                检测配置代理、候选代理与直连的可用性和延迟后退出, 不下载任何文件
+  update-sub-store self-update [选项]
+                                将本工具更新到 -self-repo 的最新 release (校验 sha256 后替换), 可配合 -dry-run 只检查
+
+退出码:
+  0  已是最新, 或更新成功
+  1  运行出错
+  2  参数错误
+  3  检查模式 (-dry-run / check) 下发现可用更新
+  4  release 中未找到所需资源
+  5  下载资源失败
+  6  git 提交或推送失败
+  7  代理与直连均不可用
+  8  verify 发现已提交文件与上游不一致
+  9  指定 -fail-on-no-update 时已是最新, 没有任何更新 (包括因 -min-commit-interval 推迟的更新)
+  130  运行中被 Ctrl-C / SIGTERM 中断 (已删除临时文件; 守护模式等待期间中断视为正常退出)
+
+守护模式:
+  -watch 指定检查间隔后持续运行, 单次失败不会退出; -watch-jitter 为每次等待增加随机时长,
+  多个实例使用相同间隔时可错开对 GitHub API 的请求, 例如 -watch 1h -watch-jitter 5m。
+
+配置文件:
+  -config 指定 JSON 文件, 键名与下列参数名相同, 命令行参数优先于配置文件。
+  字符串取值支持 $VAR / ${VAR} 环境变量展开, 例如:
+    {"dest": "${HOME}/subs-check/assets", "token": "${GITHUB_TOKEN}", "push": true}
+
+选项:
+`
+
+// parseFlags 解析命令行参数
+// 指定 -config 时先应用配置文件, 命令行中显式给出的参数优先于配置文件
+func parseFlags(args []string) (*Config, error) {
+	cfg := &Config{
+		Command:           "update",
+		Retry:             defaultRetryPolicy(),
+		Assets:            []string{"sub-store.bundle.js"},
+		InputCompressions: []string{"gz", "bz2", "zst"},
+	}
+	if len(args) > 0 {
+		switch args[0] {
+		case "check":
+			cfg.Command = args[0]
+			cfg.DryRun = true
+			args = args[1:]
+		case "verify":
+			cfg.Command = args[0]
+			cfg.DryRun = true
+			args = args[1:]
+		case "clean", "commit-only", "config", "list-proxies", "self-update":
+			cfg.Command = args[0]
+			args = args[1:]
+		}
+	}
+
+	fs := newFlagSet(cfg)
+	cfg.flags = fs
+	var fileErr error
+	if path := lookupFlagValue(args, "config"); path != "" {
+		fileErr = applyConfigFile(fs, path)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if cfg.Lang == langEN {
+		lang = langEN
+	}
+	if cfg.NoCompress {
+		cfg.Format = formatJS
+	}
+	if cfg.Output == stdoutOutput {
+		cfg.NoCommit = true
+	}
+	if cfg.List > 0 || cfg.DryRunGit {
+		cfg.DryRun = true
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("GITHUB_TOKEN")
+	}
+
+	// git 操作期间会切换工作目录，路径需使用绝对路径
+	for _, p := range []*string{&cfg.StateFile, &cfg.DestDir, &cfg.RepoPath, &cfg.Worktree, &cfg.DownloadCache} {
+		if *p == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(*p); err == nil {
+			*p = abs
+		}
+	}
+
+	if err := errors.Join(fileErr, cfg.validate()); err != nil {
+		fmt.Fprintf(fs.Output(), tr("配置有误:\n%v\n"), err)
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// newFlagSet 定义所有命令行参数, 参数名同时也是配置文件中的键名
+func newFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("update-sub-store", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usageHeader)
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&cfg.ConfigFile, "config", "", "JSON 配置文件路径, 格式见上文")
+	fs.BoolVar(&cfg.Push, "push", false, "提交后推送到远程仓库")
+	fs.BoolVar(&cfg.Push, "p", false, "-push 的简写")
+	fs.StringVar(&cfg.CommitType, "commit-type", "chore", "提交信息的 conventional commit 类型, 如 chore / feat / deps")
+	fs.StringVar(&cfg.CommitScope, "commit-scope", "", "提交信息的 scope, 为空时使用组件名 (sub-store / sub-store-frontend), 为 - 时不带 scope")
+	fs.StringVar(&cfg.Branch, "branch", "main", "目标仓库的分支, 提交前会确认当前位于该分支, 推送时也使用该分支")
+	fs.BoolVar(&cfg.CheckoutBranch, "checkout-branch", false, "目标仓库不在 -branch 分支时自动切换, 而不是中止")
+	fs.BoolVar(&cfg.RetryPush, "retry-push", false, "运行前检测本地未推送的提交并重新推送 (需同时指定 -push)")
+	fs.BoolVar(&cfg.VerifyPush, "verify-push", false, "推送后通过 GitHub API 确认远程分支的最新提交与本地一致 (需同时指定 -push 和 -token)")
+	fs.BoolVar(&cfg.Amend, "amend", false, "HEAD 为本工具当天生成的同一组件更新提交时修订该提交而不是新建提交; 该提交已推送时以 --force-with-lease 推送")
+	fs.BoolVar(&cfg.NoTrailers, "no-trailers", false, "提交信息中不附加 Sub-Store-Version / Bundle-SHA256 / Generated-by trailer (不能与 -amend 同时使用)")
+	fs.IntVar(&cfg.PushAttempts, "push-attempts", 3, "git 推送的总尝试次数, 按 -retry-delay 指数退避; 提交本身不会重试")
+	fs.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "仅检查是否有可用更新, 不写入文件、不提交 (有更新时退出码为 3)")
+	fs.BoolVar(&cfg.FailOnNoUpdate, "fail-on-no-update", false, "已是最新、没有任何更新时以退出码 9 退出, 便于 CI 发现调度异常; 守护模式下无效")
+	fs.BoolVar(&cfg.DryRunGit, "dry-run-git", false, "检查模式下同时校验 git 操作: 以 git add --dry-run 报告将提交的文件, 不修改索引和工作区 (隐含 -dry-run)")
+	fs.StringVar(&cfg.DestDir, "dest", defaultDestDir(), "目标目录, 须位于 git 仓库中, 未指定 -repo-path 时其上级目录即为仓库根目录")
+	fs.StringVar(&cfg.PathTemplate, "path-template", "", "目标文件在 -dest 中的相对路径模板 (text/template), 可用 {{.Tag}} {{.Name}} {{.Component}}, 如 {{.Tag}}/{{.Name}} 使每个版本写入不同路径; 为空时使用文件名 {{.Name}}")
+	fs.StringVar(&cfg.RepoPath, "repo-path", "", "git 仓库根目录, -dest 须位于其中; 为空时使用 -dest 的上级目录. 也可以是裸仓库, 此时文件按相对 -dest 上级目录的路径在工作树中提交")
+	fs.StringVar(&cfg.Worktree, "worktree", "", "-repo-path 为裸仓库时用于提交的工作树目录, 不存在时自动创建, 现有改动会被丢弃; 为空时每次提交创建临时工作树并在提交后删除")
+	fs.StringVar(&cfg.MetaStripPrefix, "metadata-strip-prefix", "", "元数据 path 字段去掉的仓库内路径前缀, 如 assets/")
+	fs.StringVar(&cfg.MetaPrefix, "metadata-prefix", "", "去掉 -metadata-strip-prefix 后在元数据 path 字段前添加的前缀, 使引用路径与提交路径不同")
+	fs.StringVar(&cfg.LogFile, "log-file", "", "将日志写入该文件而不是标准错误, 按 -log-max-mb 轮转, 适合守护模式长期运行")
+	fs.Int64Var(&cfg.LogMaxMB, "log-max-mb", 10, "-log-file 单个文件的大小上限 (MiB), 超过时轮转, 0 表示不轮转")
+	fs.IntVar(&cfg.LogKeep, "log-keep", 3, "-log-file 轮转后保留的旧文件数 (文件名后缀 .1 .2 ...)")
+	fs.StringVar(&cfg.VerifyCommand, "verify-command", "", "提交后、推送前在仓库目录中通过 shell 执行的校验命令 (如 subs-check 的试运行), 以非零状态退出时回滚本次提交; 环境变量 UPDATE_SUB_STORE_COMPONENT / UPDATE_SUB_STORE_TAG 为组件名和版本")
+	fs.StringVar(&cfg.DumpRelease, "dump-release", "", "将 GitHub API 返回的 release 原始 JSON 写入该文件 (以请求地址为键), 用于排查资源选择等问题")
+	fs.StringVar(&cfg.Report, "report", "", "运行结束后将摘要 (版本、是否更新/提交/推送、文件大小与哈希、耗时、代理、警告) 写入该文件, 扩展名为 .md 时为 Markdown, 否则为 JSON")
+	fs.Float64Var(&cfg.MaxSizeChange, "max-size-change", 0, "新文件大小与元数据记录的上次大小相差超过该百分比 (如 50) 时拒绝更新, 0 表示不检查")
+	fs.BoolVar(&cfg.Force, "force", false, "-max-size-change 检查不通过时仍然更新, 只记录警告")
+	fs.BoolVar(&cfg.NoCommit, "no-commit", false, "只写入目标文件, 不执行 git 提交")
+	fs.BoolVar(&cfg.NoCompress, "no-compress", false, "不压缩后端文件, 等同于 -format js")
+	fs.StringVar(&cfg.Format, "format", formatZst, "后端文件输出格式: zst / js / both")
+	fs.Func("asset", "要下载的后端资源名称, 逗号分隔, 支持 * ? [] 通配; 所有匹配的资源一并下载并在同一次提交中更新 (默认 sub-store.bundle.js)", func(v string) error {
+		cfg.Assets = splitList(v)
+		return nil
+	})
+	fs.StringVar(&cfg.AssetRegex, "asset-regex", "", "用正则表达式选择要下载的后端资源, 指定时代替 -asset, 适用于文件名带版本号的资源; 多个匹配时取 updated_at 最新、其次名称最小的一个")
+	fs.StringVar(&cfg.Input, "input", "", "使用本地后端文件代替下载, 跳过网络访问, 只更新后端; 输出文件名取自该文件名, - 表示从标准输入读取 sub-store.bundle.js")
+	fs.StringVar(&cfg.Output, "o", "", "设为 - 时将处理后的后端文件写到标准输出而不是目标目录, 不写元数据、不提交, 日志输出到标准错误")
+	fs.StringVar(&cfg.Tag, "tag", "local", "使用 -input 时记录在元数据和提交信息中的版本")
+	fs.StringVar(&cfg.CompressPattern, "compress-pattern", "*.js", "按 -format 压缩的后端文件名模式, 其余文件原样提交")
+	fs.BoolVar(&cfg.IncludeSourcemap, "include-sourcemap", false, "release 中有 source map 时一并下载、校验并按 -format 压缩, 与后端文件在同一次提交中更新")
+	fs.StringVar(&cfg.SourcemapName, "sourcemap-name", "sub-store.bundle.js.map", "-include-sourcemap 使用的 source map 资源名称")
+	fs.DurationVar(&cfg.WaitAsset, "wait-asset", 0, "release 已发布但资源尚未上传时, 最长等待该时间并定期重新检查, 0 表示立即报错")
+	fs.StringVar(&cfg.MatchField, "match-field", matchName, "选择资源时匹配的字段: name (文件名) / label (显示名称)")
+	fs.Func("input-compressions", "识别为上游压缩格式的资源扩展名, 逗号分隔, 下载后先解压再处理 (默认 gz,bz2,zst, 置空则不解压)", func(v string) error {
+		cfg.InputCompressions = splitList(v)
+		return nil
+	})
+	fs.IntVar(&cfg.DownloadWorkers, "download-workers", 4, "同时下载的资源数上限")
+	fs.StringVar(&cfg.DownloadCache, "download-cache", "", "下载缓存目录, 按 sha256 保存下载的资源, API 提供摘要时直接复用, 否则以 ETag / Last-Modified 发送条件请求; 为空表示不缓存")
+	fs.BoolVar(&cfg.CleanCache, "clean-cache", false, "clean 子命令同时删除 -download-cache 中的缓存文件, 目录为空时一并删除")
+	fs.Int64Var(&cfg.CacheMaxMB, "download-cache-max-mb", 200, "下载缓存的总大小上限 (MiB), 超过时删除最久未使用的文件")
+	fs.Int64Var(&cfg.MaxDownloadMB, "max-download-mb", 100, "单个下载文件的大小上限 (MiB), 超过时中止下载, 0 表示不限制")
+	fs.IntVar(&cfg.MaxRedirects, "max-redirects", 10, "下载时最多跟随的跳转次数, 超过或出现循环跳转时报错; 配合 -verbose 输出每次跳转")
+	fs.Int64Var(&cfg.MaxAssetMB, "max-asset-size", 0, "下载前按 API 返回的资源大小检查的上限 (MiB), 超过时不下载并报错, 0 表示不检查")
+	fs.StringVar(&cfg.Level, "level", "default", "zstd 压缩级别: fastest / default / better / best")
+	fs.IntVar(&cfg.CompressThreads, "compress-threads", defaultCompressThreads(), "zstd 压缩使用的 goroutine 数, 对前端归档和 16 MiB 以上的后端文件生效, 不影响压缩结果")
+	fs.StringVar(&cfg.Proxy, "proxy", "http://127.0.0.1:10808", "优先检测的代理地址, 不可用时再扫描常见端口")
+	fs.StringVar(&cfg.GPGKey, "gpg-key", "", "OpenPGP 公钥文件 (ASCII armor 或二进制), 指定后下载资源时同时下载 release 中的 .asc / .sig 分离签名并校验, 缺少签名或校验失败时中止")
+	fs.StringVar(&cfg.Token, "token", "", "访问 GitHub API 使用的 token, 默认读取环境变量 GITHUB_TOKEN")
+	fs.StringVar(&cfg.AppID, "app-id", "", "以 GitHub App 身份认证时的 App ID, 需同时指定 -app-key; 指定后代替 -token 用于 API 请求和推送")
+	fs.StringVar(&cfg.AppKey, "app-key", "", "GitHub App 的 PEM 私钥文件路径")
+	fs.StringVar(&cfg.AppInstallation, "app-installation-id", "", "GitHub App 的安装 ID, 为空时查找目标仓库 origin 对应的安装")
+	fs.StringVar(&cfg.ProxyConfig, "proxy-config", "", "从 subs-check 或 Clash 的 YAML 配置中读取代理 (system-proxy / mixed-port / port), 读取成功时代替 -proxy 优先检测")
+	fs.Func("proxy-test-url", "检测代理时访问的目标及期望状态码, 格式为 URL=状态码 (省略时为 200), 可重复指定, 指定后代替内置的 Google 204 与 GitHub Raw 检测; 配置文件中也可写为 {\"url\": ..., \"expectCode\": 204}", func(v string) error {
+		t, err := parseTestTarget(v)
+		if err != nil {
+			return err
+		}
+		cfg.ProxyTestTargets = append(cfg.ProxyTestTargets, t)
+		return nil
+	})
+	fs.DurationVar(&cfg.ProxyTimeout, "proxy-detect-timeout", 20*time.Second, "整个代理检测阶段的最长时间, 超时后使用已得到的结果, 都不可用时检测直连; 0 表示不限制")
+	fs.StringVar(&cfg.ProxyFor, "proxy-for", proxyForBoth, "找到的代理用于哪些请求: both (API 与资源下载) / api (只用于 API, 资源直连下载) / download (只用于资源下载, API 直连)")
+	fs.IntVar(&cfg.ProxySamples, "proxy-samples", 1, "检测全部代理并按延迟选择时, 每个可用代理的延迟采样次数, 取平均值以减少网络抖动的影响")
+	fs.IntVar(&cfg.ProxyAttempts, "proxy-probe-attempts", 1, "每个代理判定为不可用前的检测次数, 大于 1 时可减少繁忙代理被误判, 但会延长检测时间")
+	fs.BoolVar(&cfg.ForceProxyScan, "force-proxy-scan", false, "不优先使用 -proxy, 完整检测所有候选代理并输出每个结果")
+	fs.Func("proxy-candidate", "额外检测的候选代理, 逗号分隔, 可重复指定; 只写端口时视为 http://127.0.0.1:端口", func(v string) error {
+		cfg.ProxyCandidates = append(cfg.ProxyCandidates, splitList(v)...)
+		return nil
+	})
+	fs.StringVar(&cfg.ProxyFile, "proxy-file", "", "从文件读取额外的候选代理, 每行一个, 格式同 -proxy-candidate, 忽略空行和 # 注释")
+	fs.Func("exclude-proxy", "从内置候选代理中移除的代理, 格式同 -proxy-candidate, 可重复指定", func(v string) error {
+		cfg.ExcludeProxies = append(cfg.ExcludeProxies, splitList(v)...)
+		return nil
+	})
+	fs.Func("proxy-allow", "只允许使用匹配的代理 (含 -proxy 与 -proxy-config), 可为 CIDR 如 127.0.0.0/8 或通配符如 127.0.0.1:*, 逗号分隔, 可重复指定", func(v string) error {
+		cfg.ProxyAllow = append(cfg.ProxyAllow, splitList(v)...)
+		return nil
+	})
+	fs.Func("proxy-deny", "禁止使用匹配的代理, 格式同 -proxy-allow, 优先于 -proxy-allow", func(v string) error {
+		cfg.ProxyDeny = append(cfg.ProxyDeny, splitList(v)...)
+		return nil
+	})
+	fs.StringVar(&cfg.ManifestURL, "manifest-url", "", "版本清单 JSON 地址, 如 {\"sub-store\": \"2.19.0\"}; 列出的组件只更新到清单批准的版本")
+	fs.Int64Var(&cfg.MinFreeMB, "min-free-mb", 10, "写入前要求目标文件系统在容纳新文件之外至少还剩余的空间 (MiB), 0 表示不检查")
+	fs.BoolVar(&cfg.DiffLines, "diff-lines", false, "提交前额外统计新旧 js 文件 (解压后) 的行数变化, 文件较大时较慢")
+	fs.BoolVar(&cfg.SyncMetadata, "sync-metadata", false, "目标文件未变化但元数据缺失或过期时, 只更新并提交元数据 (会关闭按资源摘要和版本跳过下载的优化)")
+	fs.StringVar(&cfg.UploadURL, "upload-url", "", "更新后将产物及其校验文件 (如 .sha256) 通过 HTTP PUT 上传到该地址下 (可在 URL 中携带 Basic 认证信息)")
+	fs.StringVar(&cfg.UploadToken, "upload-token", "", "上传时使用的 Bearer token")
+	fs.StringVar(&cfg.StateFile, "state", "update-sub-store.state.json", "状态文件路径")
+	fs.DurationVar(&cfg.CacheTTL, "cache-ttl", 5*time.Minute, "latest release 信息的本地缓存有效期, 0 表示不缓存")
+	fs.BoolVar(&cfg.NoCache, "no-cache", false, "忽略本地 release 缓存, 总是请求 GitHub API; 同时不再因后端资源摘要未变而跳过下载")
+	fs.DurationVar(&cfg.MinCommitInterval, "min-commit-interval", 0, "同一组件两次提交之间的最小间隔, 0 表示不限制")
+	fs.DurationVar(&cfg.Watch, "watch", 0, "以守护模式运行, 每隔该时间检查一次更新, 0 表示只运行一次")
+	fs.StringVar(&cfg.MetricsAddr, "metrics-addr", "", "守护模式下在该地址 (如 :9090) 的 /metrics 提供 Prometheus 指标, 为空表示不启用")
+	fs.BoolVar(&cfg.Once, "once", false, "只运行一次检查后退出, 忽略配置文件或命令行中的 -watch")
+	fs.IntVar(&cfg.WatchMaxFailures, "watch-max-failures", 0, "守护模式下连续失败达到该次数后退出, 0 表示一直重试")
+	fs.DurationVar(&cfg.WatchJitter, "watch-jitter", 0, "守护模式下每次等待额外增加 0 到该时间之间的随机值, 避免多个实例同时请求")
+	fs.IntVar(&cfg.Retry.Attempts, "retry-attempts", cfg.Retry.Attempts, "获取 release 和下载文件的总尝试次数")
+	fs.DurationVar(&cfg.Retry.BaseDelay, "retry-delay", cfg.Retry.BaseDelay, "首次重试前的等待时间, 之后每次翻倍")
+	fs.DurationVar(&cfg.Retry.MaxDelay, "retry-max-delay", cfg.Retry.MaxDelay, "单次重试等待时间上限")
+	fs.Float64Var(&cfg.Retry.Jitter, "retry-jitter", cfg.Retry.Jitter, "重试等待时间的随机抖动比例 (0~1)")
+	fs.BoolVar(&cfg.Verbose, "verbose", false, "输出更详细的诊断信息, 如代理检测结果表格")
+	fs.BoolVar(&cfg.Verbose, "v", false, "-verbose 的简写")
+	fs.StringVar(&cfg.Lang, "lang", langZH, "日志与错误信息的语言: zh / en")
+	fs.StringVar(&cfg.FileMode, "file-mode", "0644", "新建目标文件使用的权限 (八进制); 替换已有文件时沿用其原有权限")
+	fs.StringVar(&cfg.HashAlgo, "hash-algo", defaultHashAlgo, "校验文件与元数据使用的哈希算法: "+hashAlgoNames()+", 校验文件以算法名为后缀")
+	fs.BoolVar(&cfg.ShowVersion, "version", false, "输出版本信息后退出")
+	fs.StringVar(&cfg.SelfRepo, "self-repo", selfRepo, "self-update 使用的本工具 release 仓库 (owner/repo)")
+	fs.IntVar(&cfg.List, "list", 0, "列出后端与前端最近 N 个 release 的版本和发布时间后退出, 不做任何修改")
+	fs.StringVar(&cfg.UserAgent, "user-agent", defaultUserAgent, "所有 HTTP 请求使用的 User-Agent")
+	fs.StringVar(&cfg.CACert, "ca-cert", "", "额外信任的 CA 证书 (PEM) 文件, 追加到系统根证书之后, 用于企业网络中会解密 HTTPS 的代理")
+	fs.BoolVar(&cfg.InsecureTLS, "insecure-skip-verify", false, "不校验 HTTPS 证书 (不安全, 仅用于排查问题, 优先使用 -ca-cert)")
+	fs.BoolVar(&cfg.SinceLastRun, "since-last-run", false, "记录每个组件上次成功运行的时间, release 与资源都早于该时间且目标目录已是该版本时跳过下载")
+	fs.Func("since", "只应用该时间之后发布的 release, 格式为 2006-01-02 或 RFC3339", func(v string) error {
+		t, err := parseSince(v)
+		if err != nil {
+			return err
+		}
+		cfg.Since = t
+		return nil
+	})
+	fs.Func("mirror", "下载失败时使用的镜像前缀, 可重复指定; 包含 {url} 时替换为原始地址, 否则拼接在前缀之后", func(v string) error {
+		cfg.Mirrors = append(cfg.Mirrors, v)
+		return nil
+	})
+	return fs
+}
+
+// lookupFlagValue 在解析前从参数中找出指定参数的值, 支持 -name v / -name=v / --name 形式
+func lookupFlagValue(args []string, name string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			break
+		}
+		arg = strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if v, ok := strings.CutPrefix(arg, name+"="); ok {
+			return v
+		}
+		if arg == name && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// applyConfigFile 读取 JSON 配置文件并逐项设置到参数中, 一次性报告所有错误
+// 所有字符串取值 (含列表中的字符串) 都会展开 $VAR / ${VAR} 形式的环境变量
+func applyConfigFile(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf(tr("读取配置文件 %s 失败: %w"), path, err)
+	}
+	var values map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return fmt.Errorf(tr("解析配置文件 %s 失败: %w"), path, err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		if key == "config" || key == "version" || fs.Lookup(key) == nil {
+			errs = append(errs, fmt.Errorf(tr("未知的配置项: %q"), key))
+			continue
+		}
+		items := []any{values[key]}
+		if list, ok := values[key].([]any); ok {
+			items = list
+		}
+		for _, item := range items {
+			var v string
+			switch item := item.(type) {
+			case string:
+				v = os.ExpandEnv(item)
+			case bool, json.Number:
+				v = fmt.Sprint(item)
+			case map[string]any:
+				// 对象原样转回 JSON, 由参数自行解析
+				data, _ := json.Marshal(item)
+				v = string(data)
+			default:
+				errs = append(errs, fmt.Errorf(tr("配置项 %q 的取值类型不受支持"), key))
+				continue
+			}
+			if err := fs.Set(key, v); err != nil {
+				errs = append(errs, fmt.Errorf(tr("配置项 %q 取值无效: %w"), key, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// splitList 将逗号分隔的取值拆分为列表, 忽略空白项
+func splitList(v string) []string {
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// parseTestTarget 解析 -proxy-test-url 的取值: URL=状态码、URL 或 {"url": ..., "expectCode": ...}
+func parseTestTarget(v string) (testTarget, error) {
+	t := testTarget{expectCode: http.StatusOK}
+	if strings.HasPrefix(strings.TrimSpace(v), "{") {
+		var obj struct {
+			URL        string `json:"url"`
+			ExpectCode int    `json:"expectCode"`
+		}
+		if err := json.Unmarshal([]byte(v), &obj); err != nil {
+			return t, err
+		}
+		t.url = os.ExpandEnv(obj.URL)
+		if obj.ExpectCode != 0 {
+			t.expectCode = obj.ExpectCode
+		}
+	} else if i := strings.LastIndex(v, "="); i >= 0 && len(v)-i == 4 {
+		// 只有结尾为 =三位数字 时才视为状态码, 以免误拆 URL 中的查询参数
+		n, err := strconv.Atoi(v[i+1:])
+		if err != nil {
+			t.url = v
+		} else {
+			t.url, t.expectCode = v[:i], n
+		}
+	} else {
+		t.url = v
+	}
+	if u, err := url.Parse(t.url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return t, fmt.Errorf(tr("无效的检测地址 %q"), t.url)
+	}
+	if t.expectCode < 100 || t.expectCode > 599 {
+		return t, fmt.Errorf(tr("无效的状态码 %d, 应在 100~599 之间"), t.expectCode)
+	}
+	return t, nil
+}
+
+// parseSince 解析 -since 的取值, 仅有日期时按本地时区的零点处理
+func parseSince(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(tr("无法解析时间 %q, 应为 2006-01-02 或 RFC3339 格式"), v)
+	}
+	return t, nil
+}
+
+// validate 检查参数取值是否合法, 收集所有问题后一并返回
+func (c *Config) validate() error {
+	var errs []error
+	switch c.Format {
+	case formatZst, formatJS, formatBoth:
+	default:
+		errs = append(errs, fmt.Errorf(tr("无效的 -format: %q"), c.Format))
+	}
+	if len(c.Assets) == 0 {
+		errs = append(errs, errors.New(tr("-asset 不能为空")))
+	}
+	for _, pattern := range c.Assets {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf(tr("无效的 -asset %q: %w"), pattern, err))
+		}
+	}
+	if c.AssetRegex != "" {
+		re, err := regexp.Compile(c.AssetRegex)
+		if err != nil {
+			errs = append(errs, fmt.Errorf(tr("无效的 -asset-regex %q: %w"), c.AssetRegex, err))
+		}
+		c.assetRegex = re
+	}
+	if c.PathTemplate != "" {
+		tmpl, err := template.New("path").Parse(c.PathTemplate)
+		if err == nil {
+			c.pathTmpl = tmpl
+			// 以示例值渲染一次, 尽早发现引用了不存在字段或渲染到 -dest 之外的模板
+			_, err = c.outputPath(c.DestDir, "sub-store", "v0.0.0", "sub-store.bundle.js")
+		}
+		if err != nil {
+			c.pathTmpl = nil
+			errs = append(errs, fmt.Errorf(tr("无效的 -path-template %q: %w"), c.PathTemplate, err))
+		}
+	}
+	if c.Input != "" && c.Input != stdinInput {
+		if info, err := os.Stat(c.Input); err != nil {
+			errs = append(errs, fmt.Errorf(tr("无效的 -input: %w"), err))
+		} else if info.IsDir() {
+			errs = append(errs, fmt.Errorf(tr("无效的 -input: %s 是目录"), c.Input))
+		}
+	}
+	if _, err := path.Match(c.CompressPattern, ""); err != nil {
+		errs = append(errs, fmt.Errorf(tr("无效的 -compress-pattern: %w"), err))
+	}
+	if c.Lang != langZH && c.Lang != langEN {
+		errs = append(errs, fmt.Errorf(tr("无效的 -lang: %q"), c.Lang))
+	}
+	if msg := commitMessage(c, "sub-store", "2.0.0"); !conventionalCommit.MatchString(msg) {
+		errs = append(errs, fmt.Errorf(tr("-commit-type %q 与 -commit-scope %q 生成的提交信息 %q 不符合 conventional commit 格式"), c.CommitType, c.CommitScope, msg))
+	}
+	if c.CompressThreads < 1 {
+		errs = append(errs, fmt.Errorf(tr("-compress-threads 至少为 1: %d"), c.CompressThreads))
+	}
+	if c.Amend && c.NoTrailers {
+		errs = append(errs, errors.New(tr("-amend 依靠 Generated-by trailer 识别本工具生成的提交, 不能与 -no-trailers 同时使用")))
+	}
+	if (c.AppID == "") != (c.AppKey == "") {
+		errs = append(errs, errors.New(tr("-app-id 与 -app-key 需要同时指定")))
+	}
+	if c.VerifyPush && (!c.Push || (c.Token == "" && c.AppID == "")) {
+		errs = append(errs, errors.New(tr("-verify-push 需要同时指定 -push 和 -token (或 GITHUB_TOKEN)")))
+	}
+	if c.RepoPath != "" && !isWithin(c.RepoPath, c.DestDir) && !isBareRepo(c.RepoPath) {
+		errs = append(errs, fmt.Errorf(tr("-dest %s 不在 -repo-path %s 之内"), c.DestDir, c.RepoPath))
+	}
+	if m, err := strconv.ParseUint(c.FileMode, 8, 32); err != nil || m&^0777 != 0 {
+		errs = append(errs, fmt.Errorf(tr("无效的 -file-mode: %q, 应为 0644 形式的八进制权限"), c.FileMode))
+	}
+	if _, ok := hashAlgos[c.HashAlgo]; !ok {
+		errs = append(errs, fmt.Errorf(tr("无效的 -hash-algo: %q, 可选 %s"), c.HashAlgo, hashAlgoNames()))
+	}
+	if c.MatchField != matchName && c.MatchField != matchLabel {
+		errs = append(errs, fmt.Errorf(tr("无效的 -match-field: %q"), c.MatchField))
+	}
+	for _, ext := range c.InputCompressions {
+		if _, ok := inputDecoders[ext]; !ok {
+			errs = append(errs, fmt.Errorf(tr("-input-compressions 中的 %q 不受支持"), ext))
+		}
+	}
+	if c.CacheMaxMB < 0 {
+		errs = append(errs, fmt.Errorf(tr("-download-cache-max-mb 不能为负数: %d"), c.CacheMaxMB))
+	}
+	if c.LogMaxMB < 0 || c.LogKeep < 0 {
+		errs = append(errs, fmt.Errorf(tr("-log-max-mb 与 -log-keep 不能为负数: %d, %d"), c.LogMaxMB, c.LogKeep))
+	}
+	if c.MaxSizeChange < 0 {
+		errs = append(errs, fmt.Errorf(tr("-max-size-change 不能为负数: %g"), c.MaxSizeChange))
+	}
+	if c.MaxRedirects < 0 {
+		errs = append(errs, fmt.Errorf(tr("-max-redirects 不能为负数: %d"), c.MaxRedirects))
+	}
+	if c.MaxAssetMB < 0 {
+		errs = append(errs, fmt.Errorf(tr("-max-asset-size 不能为负数: %d"), c.MaxAssetMB))
+	}
+	if c.MaxDownloadMB < 0 {
+		errs = append(errs, fmt.Errorf(tr("-max-download-mb 不能为负数: %d"), c.MaxDownloadMB))
+	}
+	if c.MinFreeMB < 0 {
+		errs = append(errs, fmt.Errorf(tr("-min-free-mb 不能为负数: %d"), c.MinFreeMB))
+	}
+	if c.PushAttempts < 1 {
+		errs = append(errs, fmt.Errorf(tr("-push-attempts 至少为 1: %d"), c.PushAttempts))
+	}
+	if c.DownloadWorkers < 1 {
+		errs = append(errs, fmt.Errorf(tr("-download-workers 至少为 1: %d"), c.DownloadWorkers))
+	}
+	if ok, _ := zstd.EncoderLevelFromString(c.Level); !ok {
+		errs = append(errs, fmt.Errorf(tr("无效的 -level: %q"), c.Level))
+	}
+	if c.Watch < 0 || c.WatchJitter < 0 {
+		errs = append(errs, errors.New(tr("-watch 和 -watch-jitter 不能为负数")))
+	}
+	if c.WatchMaxFailures < 0 {
+		errs = append(errs, fmt.Errorf(tr("-watch-max-failures 不能为负数: %d"), c.WatchMaxFailures))
+	}
+	if c.Retry.Jitter < 0 || c.Retry.Jitter > 1 {
+		errs = append(errs, fmt.Errorf(tr("-retry-jitter 需在 0~1 之间: %v"), c.Retry.Jitter))
+	}
+	if c.Proxy != "" {
+		if err := validateProxyURL(c.Proxy); err != nil {
+			errs = append(errs, fmt.Errorf(tr("无效的 -proxy: %w"), err))
+		}
+	}
+	for _, p := range slices.Concat(c.ProxyAllow, c.ProxyDeny) {
+		var err error
+		if strings.Contains(p, "/") {
+			_, _, err = net.ParseCIDR(p)
+		} else {
+			_, err = path.Match(p, "")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf(tr("无效的代理匹配规则: %q"), p))
+		}
+	}
+	for _, p := range slices.Concat(c.ProxyCandidates, c.ExcludeProxies) {
+		if err := validateProxyURL(normalizeProxy(p)); err != nil {
+			errs = append(errs, fmt.Errorf(tr("无效的候选代理: %w"), err))
+		}
+	}
+	switch c.ProxyFor {
+	case proxyForBoth, proxyForAPI, proxyForDownload:
+	default:
+		errs = append(errs, fmt.Errorf(tr("无效的 -proxy-for: %q"), c.ProxyFor))
+	}
+	if c.ProxySamples < 1 {
+		errs = append(errs, fmt.Errorf(tr("-proxy-samples 至少为 1: %d"), c.ProxySamples))
+	}
+	if c.ProxyAttempts < 1 {
+		errs = append(errs, fmt.Errorf(tr("-proxy-probe-attempts 至少为 1: %d"), c.ProxyAttempts))
+	}
+	if c.ProxyTimeout < 0 {
+		errs = append(errs, fmt.Errorf(tr("-proxy-detect-timeout 不能为负数: %s"), c.ProxyTimeout))
+	}
+	if c.GPGKey != "" {
+		keys, err := loadPGPKeys(c.GPGKey)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		c.pgpKeys = keys
+	}
+	if c.ProxyFile != "" {
+		proxies, err := readProxyFile(c.ProxyFile)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		c.fileProxy = proxies
+	}
+	if c.ManifestURL != "" {
+		if u, err := url.Parse(c.ManifestURL); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf(tr("无效的 -manifest-url: %q"), c.ManifestURL))
+		}
+	}
+	if c.UploadURL != "" {
+		if u, err := url.Parse(c.UploadURL); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf(tr("无效的 -upload-url: %q"), c.UploadURL))
+		}
+	}
+	if c.Output != "" && c.Output != stdoutOutput {
+		errs = append(errs, fmt.Errorf(tr("-o 目前只支持 - (标准输出): %q"), c.Output))
+	}
+	if !c.DryRun && c.Command == "update" && c.Output == "" {
+		if err := checkWritable(c.DestDir); err != nil {
+			errs = append(errs, fmt.Errorf(tr("目标目录不可写: %w"), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// encoderLevel 返回配置的 zstd 压缩级别
+func (c *Config) encoderLevel() zstd.EncoderLevel {
+	_, level := zstd.EncoderLevelFromString(c.Level)
+	return level
+}
+
+// repoPath 返回 git 仓库根目录, 未指定 -repo-path 时为目标目录的上级目录
+func (c *Config) repoPath() string {
+	if c.RepoPath != "" {
+		return c.RepoPath
+	}
+	return filepath.Dir(c.DestDir)
+}
+
+// pathVars 为 -path-template 中可用的变量
+type pathVars struct {
+	Tag       string // release 的 tag, 如 2.19.0
+	Name      string // 默认的输出文件名, 如 sub-store.bundle.js.zst
+	Component string // 组件名, 如 sub-store
+}
+
+// outputPath 返回组件文件 name 在 destDir 中的路径, 指定了 -path-template 时按模板渲染相对路径
+// 渲染结果须为 destDir 之内的相对路径
+func (c *Config) outputPath(destDir, component, tag, name string) (string, error) {
+	if c.pathTmpl == nil {
+		return filepath.Join(destDir, name), nil
+	}
+	var b strings.Builder
+	if err := c.pathTmpl.Execute(&b, pathVars{Tag: tag, Name: name, Component: component}); err != nil {
+		return "", err
+	}
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimSpace(b.String())))
+	if rel == "." || filepath.IsAbs(rel) || !isWithin(destDir, filepath.Join(destDir, rel)) {
+		return "", fmt.Errorf(tr("路径模板渲染为 %q, 不是 -dest 之内的相对路径"), b.String())
+	}
+	return filepath.Join(destDir, rel), nil
+}
+
+// metadataRef 返回元数据中记录的文件路径: 文件在仓库内的相对路径 (使用 /),
+// 去掉 -metadata-strip-prefix 后再加上 -metadata-prefix; 提交路径不受影响
+func (c *Config) metadataRef(file string) string {
+	rel, err := filepath.Rel(c.repoPath(), file)
+	if err != nil {
+		rel = filepath.Base(file)
+	}
+	ref := strings.TrimPrefix(filepath.ToSlash(rel), c.MetaStripPrefix)
+	if c.MetaPrefix != "" {
+		ref = path.Join(c.MetaPrefix, ref)
+	}
+	return ref
+}
+
+// compressible 判断后端资源解压后的文件 name 是否按 -format 压缩: 匹配 -compress-pattern,
+// 或为 -include-sourcemap 的 source map (资源本身压缩发布时 name 为去掉压缩扩展名后的名称)
+func (c *Config) compressible(name string) bool {
+	if c.IncludeSourcemap && (name == c.SourcemapName ||
+		slices.ContainsFunc(c.InputCompressions, func(ext string) bool { return name+"."+ext == c.SourcemapName })) {
+		return true
+	}
+	ok, _ := path.Match(c.CompressPattern, name)
+	return ok
+}
+
+// fileMode 返回 -file-mode 对应的文件权限, 取值已在 validate 中校验
+func (c *Config) fileMode() os.FileMode {
+	m, _ := strconv.ParseUint(c.FileMode, 8, 32)
+	return os.FileMode(m)
+}
+
+// pushPolicy 返回 git 推送使用的重试策略: 与 -retry-* 相同的退避参数, 尝试次数取 -push-attempts
+func (c *Config) pushPolicy() RetryPolicy {
+	p := c.Retry
+	p.Attempts = c.PushAttempts
+	return p
+}
+
+// validateProxyURL 检查代理地址是否为受支持的 URL
+func validateProxyURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf(tr("%q 的协议不受支持"), raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf(tr("%q 缺少主机"), raw)
+	}
+	return nil
+}
+
+// checkWritable 检查 dir (或其最近的已存在上级目录) 是否可写
+func checkWritable(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf(tr("%s 不是目录"), dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+	f, err := os.CreateTemp(dir, ".update-sub-store-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// defaultCompressThreads 返回默认的压缩 goroutine 数: GOMAXPROCS, 最多 4 个
+// 更多的 goroutine 对单个文件的压缩提升有限, 只会增加内存占用
+func defaultCompressThreads() int {
+	return min(runtime.GOMAXPROCS(0), 4)
+}
+
+// defaultDestDir 返回默认目标目录: $HOME/subs-check/assets, 无法获取家目录时使用相对路径 assets
+func defaultDestDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "assets"
+	}
+	return filepath.Join(home, "subs-check", "assets")
+}
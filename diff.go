@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// fileDiff 是单个文件新旧版本的概要差异
+type fileDiff struct {
+	name     string
+	oldSize  int64
+	newSize  int64
+	lines    bool // 是否统计了行数
+	oldLines int
+	newLines int
+}
+
+// diffFile 比较目标目录中现有文件与即将写入的数据
+// withLines 为真且文件为 js (含 .js.zst) 时, 额外统计解压后的行数
+func diffFile(f outputFile, withLines bool) fileDiff {
+	d := fileDiff{name: filepath.Base(f.path), newSize: int64(len(f.data))}
+	old, err := os.ReadFile(f.path)
+	if err == nil {
+		d.oldSize = int64(len(old))
+	}
+	if !withLines || !isJSFile(f.path) {
+		return d
+	}
+	newJS, err := jsContent(f.path, f.data)
+	if err != nil {
+		return d
+	}
+	d.lines = true
+	d.newLines = bytes.Count(newJS, []byte("\n"))
+	if oldJS, err := jsContent(f.path, old); err == nil {
+		d.oldLines = bytes.Count(oldJS, []byte("\n"))
+	}
+	return d
+}
+
+// isJSFile 判断 path 是否为 js 文件或其 zstd 压缩文件
+func isJSFile(path string) bool {
+	return strings.HasSuffix(path, ".js") || strings.HasSuffix(path, ".js.zst")
+}
+
+// jsContent 返回 js 文件内容, .zst 文件先解压
+func jsContent(path string, data []byte) ([]byte, error) {
+	if !strings.HasSuffix(path, ".zst") || len(data) == 0 {
+		return data, nil
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+	return dec.DecodeAll(data, nil)
+}
+
+// String 返回如 "sub-store.bundle.js.zst: 1.20 MiB -> 1.21 MiB (+12.00 KiB), 行数 100 -> 120 (+20)" 的摘要
+func (d fileDiff) String() string {
+	s := fmt.Sprintf("%s: %s -> %s (%s)", d.name, formatSize(d.oldSize), formatSize(d.newSize), sizeDelta(d.newSize-d.oldSize))
+	if d.lines {
+		s += fmt.Sprintf(tr(", 行数 %d -> %d (%+d)"), d.oldLines, d.newLines, d.newLines-d.oldLines)
+	}
+	return s
+}
+
+// sizeDelta 格式化带符号的大小变化
+func sizeDelta(n int64) string {
+	if n < 0 {
+		return "-" + formatSize(-n)
+	}
+	return "+" + formatSize(n)
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// freeSpace 在不支持的平台上不检测可用空间, 第二个返回值为 false
+func freeSpace(dir string) (int64, bool, error) {
+	return 0, false, nil
+}
+
+// copyOwner 在不支持属主的平台上不做任何处理
+func copyOwner(path string, fi os.FileInfo) {}
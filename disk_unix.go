@@ -0,0 +1,24 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// freeSpace 返回 dir 所在文件系统中当前用户可用的字节数
+func freeSpace(dir string) (int64, bool, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, true, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), true, nil
+}
+
+// copyOwner 尽力将 path 的属主设置为与 fi 相同, 没有权限时保持不变
+func copyOwner(path string, fi os.FileInfo) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		os.Lchown(path, int(st.Uid), int(st.Gid))
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import "errors"
+
+// 主要失败类型, 可通过 errors.Is 判断, run 据此决定退出码
+var (
+	ErrAssetNotFound    = message("未找到资源")
+	ErrDownloadFailed   = message("下载失败")
+	ErrGit              = message("git 操作失败")
+	ErrProxyUnavailable = message("代理与直连均不可用")
+)
+
+// kindError 为错误附加失败类型, 错误信息保持原样不变
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// withKind 将 err 标记为 kind 类型的失败, err 为 nil 时返回 nil
+func withKind(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// exitCode 返回错误对应的进程退出码
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, ErrAssetNotFound):
+		return exitAssetNotFound
+	case errors.Is(err, ErrDownloadFailed):
+		return exitDownloadFailed
+	case errors.Is(err, ErrGit):
+		return exitGit
+	case errors.Is(err, ErrProxyUnavailable):
+		return exitProxyUnavailable
+	}
+	return exitError
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ensureGitRepo 确认 dir 位于 git 工作区内
+func ensureGitRepo(dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf(tr("%s 不是 git 仓库: %w"), dir, err)
+	}
+	c := exec.Command("git", "rev-parse", "--is-inside-work-tree")
+	c.Dir = dir
+	out, err := c.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return withKind(ErrGit, fmt.Errorf(tr("%s 不是 git 仓库"), dir))
+		}
+		return withKind(ErrGit, fmt.Errorf(tr("无法执行 git: %w"), err))
+	}
+	if strings.TrimSpace(string(out)) != "true" {
+		return withKind(ErrGit, fmt.Errorf(tr("%s 不是 git 工作区"), dir))
+	}
+	return nil
+}
+
+// currentBranch 返回 dir 所在仓库当前检出的分支名
+func currentBranch(dir string) (string, error) {
+	out, err := runGit(dir, "git rev-parse", "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// ensureBranch 确认仓库当前位于期望的分支, checkout 为真时自动切换, 否则返回错误
+func ensureBranch(dir, expected string, checkout bool) error {
+	branch, err := currentBranch(dir)
+	if err != nil {
+		return err
+	}
+	if branch == expected {
+		return nil
+	}
+	log.Printf(tr("目标仓库当前分支为 %s, 期望为 %s"), branch, expected)
+	if !checkout {
+		return withKind(ErrGit, fmt.Errorf(tr("目标仓库不在 %s 分支 (当前为 %s), 可加上 -checkout-branch 自动切换"), expected, branch))
+	}
+	if _, err := runGit(dir, "git checkout", "checkout", expected); err != nil {
+		return err
+	}
+	log.Printf(tr("已切换到 %s 分支"), expected)
+	return nil
+}
+
+// relPaths 计算各文件相对 git 目录的路径
+func relPaths(gitDir string, paths ...string) []string {
+	rels := make([]string, 0, len(paths))
+	for _, p := range paths {
+		rel, _ := filepath.Rel(gitDir, p)
+		rels = append(rels, rel)
+	}
+	return rels
+}
+
+// errPushFailed 表示提交已在本地完成, 但推送到远程仓库失败
+var errPushFailed = withKind(ErrGit, message("git 推送失败"))
+
+// runGit 在 dir 中执行 git 命令, 失败时在错误中附带命令输出
+func runGit(dir, desc string, args ...string) (string, error) {
+	return runGitEnv(nil, dir, desc, args...)
+}
+
+// runGitEnv 同 runGit, env 非空时追加到 git 进程的环境变量中
+func runGitEnv(env []string, dir, desc string, args ...string) (string, error) {
+	c := exec.Command("git", args...)
+	c.Dir = dir
+	if len(env) > 0 {
+		c.Env = append(os.Environ(), env...)
+	}
+	out, err := c.CombinedOutput()
+	if err != nil {
+		return string(out), withKind(ErrGit, fmt.Errorf(tr("%s 失败: %v\n输出: %s"), desc, err, out))
+	}
+	return string(out), nil
+}
+
+// commitMessage 返回组件更新提交的标题, 如 chore(sub-store): update to 2.19.0
+// 类型与 scope 可通过 -commit-type / -commit-scope 修改, scope 为空时使用组件名, 为 - 时省略
+func commitMessage(cfg *Config, component, tag string) string {
+	scope := cfg.CommitScope
+	switch scope {
+	case "":
+		scope = component
+	case "-":
+		return fmt.Sprintf("%s: update %s to %s", cfg.CommitType, component, tag)
+	}
+	return fmt.Sprintf("%s(%s): update to %s", cfg.CommitType, scope, tag)
+}
+
+// generatedTrailer 标记由本工具生成的提交, -amend 只会修订带有该 trailer 的提交
+const generatedTrailer = "Generated-by: update-sub-store"
+
+// amendableHead 判断 HEAD 是否为本工具当天生成的同一组件的更新提交, 是则返回其 SHA
+// 不带 Generated-by trailer 的提交 (包括手动提交) 一律不修订
+func amendableHead(cfg *Config, gitDir, component string) (string, bool) {
+	out, err := runGit(gitDir, "git log", "log", "-1", "--format=%H%n%cs%n%s%n%(trailers:key=Generated-by,valueonly)")
+	if err != nil {
+		// 仓库中还没有任何提交
+		return "", false
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) < 4 || !slices.Contains(lines[3:], "update-sub-store") {
+		return "", false
+	}
+	sha, date, subject := lines[0], lines[1], lines[2]
+	if date != time.Now().Format(time.DateOnly) {
+		return "", false
+	}
+	// 标题以 "<type>(<scope>): update to " 开头才视为同一组件的提交
+	if !strings.HasPrefix(subject, commitMessage(cfg, component, "")) {
+		return "", false
+	}
+	return sha, true
+}
+
+// conventionalCommit 匹配 conventional commit 标题: type(scope): 描述, scope 可省略
+var conventionalCommit = regexp.MustCompile(`^[a-z]+(\([\w./-]+\))?!?: \S`)
+
+// 文件在一次提交中的状态
+const (
+	fileAdded     = "added"     // 新增的文件
+	fileModified  = "modified"  // 内容有变化的已跟踪文件
+	fileUnchanged = "unchanged" // 与 HEAD 相同, 不会暂存
+)
+
+// fileChange 为单个文件在提交中的状态
+type fileChange struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// pathChanges 通过 git status 判断各文件 (相对 gitDir 的路径) 相对 HEAD 是新增、修改还是未变化
+func pathChanges(gitDir string, relPaths []string) ([]fileChange, error) {
+	// git status 输出的路径相对仓库根目录, gitDir 可能是仓库的子目录
+	prefix, err := runGit(gitDir, "git rev-parse", "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	prefix = strings.TrimSpace(prefix)
+	out, err := runGit(gitDir, "git status", append([]string{"status", "--porcelain", "-z", "--untracked-files=all", "--"}, relPaths...)...)
+	if err != nil {
+		return nil, err
+	}
+	status := make(map[string]string)
+	for _, entry := range strings.Split(out, "\x00") {
+		if len(entry) < 4 {
+			continue
+		}
+		xy, p := entry[:2], strings.TrimPrefix(entry[3:], prefix)
+		if xy == "??" || xy[0] == 'A' {
+			status[p] = fileAdded
+		} else {
+			status[p] = fileModified
+		}
+	}
+	changes := make([]fileChange, 0, len(relPaths))
+	for _, p := range relPaths {
+		st := status[filepath.ToSlash(p)]
+		if st == "" {
+			st = fileUnchanged
+		}
+		changes = append(changes, fileChange{Path: p, Status: st})
+	}
+	return changes, nil
+}
+
+// fileStatusLabels 为各状态在日志中的标签
+var fileStatusLabels = map[string]string{
+	fileAdded:     "[新增]",
+	fileModified:  "[修改]",
+	fileUnchanged: "[未变化]",
+}
+
+// commitParagraphs 返回完整提交信息的各段: 标题、非空的正文, 以及 trailers 与 Generated-by 组成的最后一段
+// 每段对应 git commit 的一个 -m 参数, 检查模式下的预览也使用同样的结果
+func commitParagraphs(cfg *Config, component, tag, body string, trailers []string) []string {
+	paragraphs := []string{commitMessage(cfg, component, tag)}
+	if body != "" {
+		paragraphs = append(paragraphs, body)
+	}
+	if !cfg.NoTrailers {
+		paragraphs = append(paragraphs, strings.Join(append(slices.Clip(trailers), generatedTrailer), "\n"))
+	}
+	return paragraphs
+}
+
+// runGitCommands 在 gitDir 中添加、提交并按需推送指定文件, body 非空时作为提交信息正文,
+// trailers 与 Generated-by 一起作为提交信息的最后一段 (-no-trailers 时省略), 见 commitParagraphs
+// 只暂存相对 HEAD 有变化的文件, 返回各文件的状态; 所有文件都未变化时不提交
+// 提交成功但推送失败时返回包装了 errPushFailed 的错误, 提交保留在本地
+// 指定了 -verify-command 时在推送前校验, 未通过时回滚提交并返回包装了 errVerifyFailed 的错误
+func runGitCommands(cfg *Config, gitDir string, relPaths []string, tag string, component string, body string, trailers []string) ([]fileChange, error) {
+	changes, err := pathChanges(gitDir, relPaths)
+	if err != nil {
+		return nil, err
+	}
+	var stage []string
+	for _, c := range changes {
+		log.Printf("%s %s", tr(fileStatusLabels[c.Status]), c.Path)
+		if c.Status != fileUnchanged {
+			stage = append(stage, c.Path)
+		}
+	}
+	if len(stage) == 0 {
+		log.Printf(tr("%s %s 的文件与已提交的内容相同, 跳过提交"), component, tag)
+		return changes, nil
+	}
+	if _, err := runGit(gitDir, tr("git 添加"), append([]string{"add", "--"}, stage...)...); err != nil {
+		return changes, err
+	}
+	commitArgs := []string{"commit"}
+	for _, p := range commitParagraphs(cfg, component, tag, body, trailers) {
+		commitArgs = append(commitArgs, "-m", p)
+	}
+
+	// 修订已推送的提交需要强制推送, 以修订前的 SHA 作为 lease, 远程有他人的新提交时推送会失败
+	var pushArgs []string
+	if cfg.Amend {
+		if sha, ok := amendableHead(cfg, gitDir, component); ok {
+			commitArgs = append(commitArgs, "--amend")
+			if cfg.Push {
+				if n, err := unpushedCommits(gitDir, cfg.Branch); err == nil && n == 0 {
+					pushArgs = append(pushArgs, "--force-with-lease="+cfg.Branch+":"+sha)
+				}
+			}
+			log.Printf(tr("修订上一次由本工具生成的提交 %s"), sha[:min(len(sha), 12)])
+		}
+	}
+	var prev string
+	if cfg.VerifyCommand != "" {
+		// 记录提交 (或修订) 前的 HEAD, 校验未通过时回滚到这里; 空仓库没有 HEAD
+		out, _ := runGit(gitDir, "git rev-parse", "rev-parse", "--verify", "-q", "HEAD")
+		prev = strings.TrimSpace(out)
+	}
+	if _, err := runGit(gitDir, tr("git 提交"), commitArgs...); err != nil {
+		return changes, err
+	}
+	if cfg.VerifyCommand != "" {
+		if err := verifyCommit(cfg, gitDir, prev, component, tag); err != nil {
+			return changes, err
+		}
+	}
+
+	log.Printf(tr("成功更新 %s 到 %s"), component, tag)
+	if !cfg.Push {
+		log.Println(tr("已完成 git 提交, 请手动推送到远程仓库"))
+		return changes, nil
+	}
+	if err := pushBranch(cfg.pushPolicy(), gitDir, cfg.Branch, pushArgs...); err != nil {
+		log.Printf(tr("已在本地完成提交, 但推送失败, 本地仓库领先于远程。可稍后手动执行 git push origin %s, 或下次运行时加上 -retry-push"), cfg.Branch)
+		return changes, fmt.Errorf(tr("%w (提交已保留在本地): %w"), errPushFailed, err)
+	}
+	if cfg.VerifyPush {
+		if err := verifyPushed(cfg, gitDir); err != nil {
+			return changes, fmt.Errorf(tr("%w: 推送后远程分支未包含本次提交: %w"), errPushFailed, err)
+		}
+	}
+	log.Println(tr("已完成 git 提交和远程仓库推送"))
+	return changes, nil
+}
+
+// dryRunGitCommands 以 git add --dry-run 报告将要提交的文件, 不修改索引和工作区
+// 尚未写入的文件按将新增处理 (--ignore-missing), 被 .gitignore 忽略的文件会导致返回错误
+func dryRunGitCommands(cfg *Config, gitDir string, relPaths []string, tag string, component string) error {
+	out, err := runGit(gitDir, "git add --dry-run", append([]string{"add", "--dry-run", "--ignore-missing", "--"}, relPaths...)...)
+	if err != nil {
+		return err
+	}
+	if out = strings.TrimSpace(out); out != "" {
+		log.Printf(tr("git add --dry-run 输出:\n%s"), out)
+	}
+	log.Printf(tr("将提交 %d 个文件: %s"), len(relPaths), strings.Join(relPaths, ", "))
+	if cfg.Amend {
+		if sha, ok := amendableHead(cfg, gitDir, component); ok {
+			log.Printf(tr("将修订上一次由本工具生成的提交 %s"), sha[:min(len(sha), 12)])
+		}
+	}
+	if cfg.Push {
+		log.Printf(tr("将推送到 origin/%s"), cfg.Branch)
+	}
+	return nil
+}
+
+// pushBranch 按重试策略将本地分支推送到 origin, extra 为附加的 git push 选项
+// 配置了 GitHub App 时以安装 token 认证
+func pushBranch(policy RetryPolicy, gitDir, branch string, extra ...string) error {
+	args := append(append([]string{"push"}, extra...), "origin", branch)
+	return policy.do(tr("git 推送"), func() error {
+		// 每次尝试重新获取, 以便 GitHub App 安装 token 在重试期间过期时自动刷新
+		env, err := gitAuthEnv()
+		if err != nil {
+			return err
+		}
+		_, err = runGitEnv(env, gitDir, tr("git 推送"), args...)
+		return err
+	})
+}
+
+// unpushedCommits 返回本地分支领先 origin 对应分支的提交数
+func unpushedCommits(gitDir, branch string) (int, error) {
+	out, err := runGit(gitDir, "git rev-list", "rev-list", "--count", "origin/"+branch+".."+branch)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(out))
+}
+
+// retryPendingPush 检测上次运行遗留的未推送提交, 存在时重新推送
+func retryPendingPush(policy RetryPolicy, gitDir, branch string) error {
+	n, err := unpushedCommits(gitDir, branch)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	log.Printf(tr("检测到 %d 个未推送的提交, 重新推送到 origin/%s..."), n, branch)
+	if err := pushBranch(policy, gitDir, branch); err != nil {
+		return fmt.Errorf("%w: %w", errPushFailed, err)
+	}
+	log.Println(tr("未推送的提交已推送到远程仓库"))
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// githubApp 为以 GitHub App 身份认证时使用的凭据, 为 nil 时使用 githubToken
+var githubApp *appAuth
+
+// appTokenMargin 为安装 token 过期前提前刷新的余量, 避免请求途中过期
+const appTokenMargin = 5 * time.Minute
+
+// appAuth 用 App 私钥签发 JWT 换取安装 token, 并在过期前自动刷新
+type appAuth struct {
+	appID          string
+	key            *rsa.PrivateKey
+	installationID string
+	repo           string // 未指定 installationID 时, 通过该仓库查找 App 的安装
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// loadAppAuth 读取 -app-id / -app-key 配置的 GitHub App 凭据
+// 未指定 -app-installation-id 时使用目标仓库 origin 对应的安装
+func loadAppAuth(cfg *Config) (*appAuth, error) {
+	data, err := os.ReadFile(cfg.AppKey)
+	if err != nil {
+		return nil, fmt.Errorf(tr("读取 GitHub App 私钥失败: %w"), err)
+	}
+	key, err := parseRSAKey(data)
+	if err != nil {
+		return nil, fmt.Errorf(tr("解析 GitHub App 私钥 %s 失败: %w"), cfg.AppKey, err)
+	}
+	a := &appAuth{appID: cfg.AppID, key: key, installationID: cfg.AppInstallation}
+	if a.installationID == "" {
+		remote, err := runGit(cfg.repoPath(), "git remote get-url", "remote", "get-url", "origin")
+		if err != nil {
+			return nil, fmt.Errorf(tr("无法确定 GitHub App 的安装, 请指定 -app-installation-id: %w"), err)
+		}
+		if a.repo, err = githubRepoFromRemote(remote); err != nil {
+			return nil, fmt.Errorf(tr("无法确定 GitHub App 的安装, 请指定 -app-installation-id: %w"), err)
+		}
+	}
+	return a, nil
+}
+
+// parseRSAKey 解析 PEM 格式的 RSA 私钥, GitHub 生成的是 PKCS#1, 也接受转换后的 PKCS#8
+func parseRSAKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New(tr("不是 PEM 格式"))
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New(tr("不是 RSA 私钥"))
+	}
+	return rsaKey, nil
+}
+
+// jwt 签发以 App 身份调用 API 的 RS256 JWT
+// iat 提前 60 秒以容忍时钟偏差, 有效期不超过 GitHub 允许的 10 分钟
+func (a *appAuth) jwt() (string, error) {
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// appRequest 以 App 身份 (JWT) 调用 GitHub API, 将 200/201 的响应解析到 v
+func (a *appAuth) appRequest(method, url string, v any) error {
+	jwt, err := a.jwt()
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return statusError(tr("GitHub App 认证失败"), resp)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// installationToken 返回有效的安装 token, 尚未签发或即将过期时重新签发
+func (a *appAuth) installationToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Until(a.expires) > appTokenMargin {
+		return a.token, nil
+	}
+
+	if a.installationID == "" {
+		var inst struct {
+			ID int64 `json:"id"`
+		}
+		if err := a.appRequest(http.MethodGet, "https://api.github.com/repos/"+a.repo+"/installation", &inst); err != nil {
+			return "", fmt.Errorf(tr("查找 GitHub App 在 %s 的安装失败: %w"), a.repo, err)
+		}
+		a.installationID = fmt.Sprint(inst.ID)
+	}
+	var tok struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	url := "https://api.github.com/app/installations/" + a.installationID + "/access_tokens"
+	if err := a.appRequest(http.MethodPost, url, &tok); err != nil {
+		return "", fmt.Errorf(tr("签发 GitHub App 安装 token 失败: %w"), err)
+	}
+	a.token, a.expires = tok.Token, tok.ExpiresAt
+	log.Printf(tr("已签发 GitHub App 安装 token, 有效期至 %s"), tok.ExpiresAt.Local().Format(time.DateTime))
+	return a.token, nil
+}
+
+// currentToken 返回访问 GitHub 时使用的 token: 配置了 GitHub App 时为安装 token, 否则为 githubToken
+func currentToken() (string, error) {
+	if githubApp != nil {
+		return githubApp.installationToken()
+	}
+	return githubToken, nil
+}
+
+// gitAuthEnv 配置了 GitHub App 时返回让 git 以安装 token 访问 github.com 的环境变量
+// 通过 GIT_CONFIG_* 传入 http.extraHeader, 避免 token 出现在命令行参数或仓库配置中
+func gitAuthEnv() ([]string, error) {
+	if githubApp == nil {
+		return nil, nil
+	}
+	token, err := githubApp.installationToken()
+	if err != nil {
+		return nil, err
+	}
+	basic := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.https://github.com/.extraheader",
+		"GIT_CONFIG_VALUE_0=AUTHORIZATION: basic " + basic,
+	}, nil
+}
@@ -2,4 +2,17 @@ module update-sub-store
 
 go 1.25.0
 
-require github.com/klauspost/compress v1.18.3
+require (
+	github.com/klauspost/compress v1.18.3
+	github.com/zeebo/blake3 v0.2.4
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require github.com/klauspost/cpuid/v2 v2.0.12 // indirect
+
+require (
+	github.com/ProtonMail/go-crypto v1.5.1
+	github.com/cloudflare/circl v1.6.3 // indirect
+	golang.org/x/crypto v0.41.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+)
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"slices"
+	"strings"
+
+	"github.com/zeebo/blake3"
+)
+
+// hashAlgos 为 -hash-algo 支持的哈希算法
+var hashAlgos = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+	"blake3": func() hash.Hash { return blake3.New() },
+}
+
+// defaultHashAlgo 是校验文件与元数据默认使用的哈希算法
+const defaultHashAlgo = "sha256"
+
+// hashAlgo 为本次运行使用的哈希算法, 可通过 -hash-algo 覆盖
+// 校验文件、元数据以及判断文件是否变化的哈希比较都使用该算法, 保证同一次运行内一致
+var hashAlgo = defaultHashAlgo
+
+// hashAlgoNames 返回支持的算法名称, 用于帮助信息和错误提示
+func hashAlgoNames() string {
+	names := make([]string, 0, len(hashAlgos))
+	for name := range hashAlgos {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, " / ")
+}
+
+// newHash 返回当前算法的 hash.Hash
+func newHash() hash.Hash {
+	return hashAlgos[hashAlgo]()
+}
+
+// hashSum 计算 data 在当前算法下的摘要
+func hashSum(data []byte) []byte {
+	h := newHash()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// hashHex 返回 data 在当前算法下的十六进制摘要
+func hashHex(data []byte) string {
+	return hex.EncodeToString(hashSum(data))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// blake3Vectors 取自 BLAKE3 官方 test_vectors.json 的默认哈希 (前 32 字节),
+// 输入为长度为 inputLen、第 i 个字节为 i % 251 的序列
+var blake3Vectors = []struct {
+	inputLen int
+	hash     string
+}{
+	{0, "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"},
+	{1, "2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213"},
+	{1023, "10108970eeda3eb932baac1428c7a2163b0e924c9a9e25b35bba72b28f70bd11"},
+	{1024, "42214739f095a406f3fc83deb889744ac00df831c10daa55189b5d121c855af7"},
+	{1025, "d00278ae47eb27b34faecf67b4fe263f82d5412916c1ffd97c8cb7fb814b8444"},
+	{2048, "e776b6028c7cd22a4d0ba182a8bf62205d2ef576467e838ed6f2529b85fba24a"},
+	{2049, "5f4d72f40d7a5f82b15ca2b2e44b1de3c2ef86c426c95c1af0b6879522563030"},
+	{3072, "b98cb0ff3623be03326b373de6b9095218513e64f1ee2edd2525c7ad1e5cffd2"},
+	{4096, "015094013f57a5277b59d8475c0501042c0b642e531b0a1c8f58d2163229e969"},
+	{8192, "aae792484c8efe4f19e2ca7d371d8c467ffb10748d8a5a1ae579948f718a2a63"},
+	{8193, "bab6c09cb8ce8cf459261398d2e7aef35700bf488116ceb94a36d0f5f1b7bc3b"},
+	{16384, "f875d6646de28985646f34ee13be9a576fd515f76b5b0a26bb324735041ddde4"},
+	{31744, "62b6960e1a44bcc1eb1a611a8d6235b6b4b78f32e7abc4fb4c6cdcce94895c47"},
+}
+
+func TestBlake3Vectors(t *testing.T) {
+	prev := hashAlgo
+	hashAlgo = "blake3"
+	t.Cleanup(func() { hashAlgo = prev })
+	for _, v := range blake3Vectors {
+		input := make([]byte, v.inputLen)
+		for i := range input {
+			input[i] = byte(i % 251)
+		}
+		if got := hashHex(input); got != v.hash {
+			t.Errorf("blake3(%d 字节) = %s, want %s", v.inputLen, got, v.hash)
+		}
+		// 分段写入应得到相同结果
+		h := newHash()
+		for chunk := input; len(chunk) > 0; {
+			n := min(len(chunk), 100)
+			h.Write(chunk[:n])
+			chunk = chunk[n:]
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); got != v.hash {
+			t.Errorf("分段写入 blake3(%d 字节) = %s, want %s", v.inputLen, got, v.hash)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+)
+
+// defaultUserAgent 是请求默认携带的 User-Agent, GitHub 建议使用可识别的客户端名称
+var defaultUserAgent = "update-sub-store/" + version
+
+// userAgent 为所有请求附带的 User-Agent, 可通过 -user-agent 覆盖
+var userAgent = defaultUserAgent
+
+// githubToken 为访问 GitHub API 时使用的 token, 为空时匿名访问
+var githubToken string
+
+// downloadTransport 为下载 release 资源使用的 Transport, 为 nil 时与 API 请求相同使用 http.DefaultTransport
+// -proxy-for 只对其中一类请求使用代理时由 setupProxy 设置
+var downloadTransport http.RoundTripper
+
+// newRequest 创建附带 User-Agent 的 HTTP 请求
+func newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	return req, nil
+}
+
+// newGitHubRequest 创建访问 GitHub API 的请求, 配置了 token 或 GitHub App 时附带认证信息
+func newGitHubRequest(method, url string) (*http.Request, error) {
+	req, err := newRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	token, err := currentToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+// acceptGzip 显式请求 gzip 压缩的响应, release 列表包含大量资源时可明显减少流量
+// 手动设置该请求头后 Transport 不再自动解压, 响应需通过 responseBody 读取
+func acceptGzip(req *http.Request) {
+	req.Header.Set("Accept-Encoding", "gzip")
+}
+
+// responseBody 返回解压后的响应体, 服务器未压缩或 Transport 已自动解压时原样返回
+// 原始响应体仍由调用方关闭
+func responseBody(resp *http.Response) (io.Reader, error) {
+	if resp.Uncompressed || resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp.Body, nil
+	}
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf(tr("解压响应失败: %w"), err)
+	}
+	return zr, nil
+}
+
+// tlsConfig 为所有 HTTPS 连接 (包括代理检测) 使用的 TLS 配置, 为 nil 时使用系统默认配置
+var tlsConfig *tls.Config
+
+// configureTLS 按 -ca-cert / -insecure-skip-verify 设置 tlsConfig 并应用到默认 Transport
+// -ca-cert 中的证书追加到系统根证书之后, 用于信任企业代理的自签 CA
+func configureTLS(caCert string, insecure bool) error {
+	if caCert == "" && !insecure {
+		return nil
+	}
+	conf := &tls.Config{}
+	if caCert != "" {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return fmt.Errorf(tr("读取 CA 证书失败: %w"), err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf(tr("%s 中没有有效的 PEM 证书"), caCert)
+		}
+		conf.RootCAs = pool
+	}
+	if insecure {
+		log.Println(tr("警告: 已指定 -insecure-skip-verify, 不校验 HTTPS 证书, 下载内容可能被篡改! 仅应在排查问题时临时使用"))
+		conf.InsecureSkipVerify = true
+	}
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return errors.New(tr("无法为默认 Transport 设置 TLS 配置"))
+	}
+	transport.TLSClientConfig = conf
+	tlsConfig = conf
+	return nil
+}
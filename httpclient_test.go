@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRequestsSendUserAgent(t *testing.T) {
+	const agent = "update-sub-store-test/1.0"
+	prevAgent, prevToken := userAgent, githubToken
+	userAgent, githubToken = agent, "test-token"
+	t.Cleanup(func() { userAgent, githubToken = prevAgent, prevToken })
+
+	var mu sync.Mutex
+	seen := make(map[string]http.Header)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.URL.Path] = r.Header.Clone()
+		mu.Unlock()
+		if r.URL.Path == "/release" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"tag_name":"v1.0.0"}`))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	if release, err := fetchRelease(srv.URL + "/release"); err != nil || release.TagName != "v1.0.0" {
+		t.Fatalf("fetchRelease = %+v, %v", release, err)
+	}
+	if _, _, err := downloadFile(srv.URL+"/download", 0, nil); err != nil {
+		t.Fatalf("downloadFile: %v", err)
+	}
+	if ok, _ := probeTargets(nil, []testTarget{{srv.URL + "/probe", http.StatusOK}}); !ok {
+		t.Fatal("probeTargets 检测失败")
+	}
+
+	for _, path := range []string{"/release", "/download", "/probe"} {
+		h, ok := seen[path]
+		if !ok {
+			t.Errorf("%s 没有收到请求", path)
+			continue
+		}
+		if got := h.Get("User-Agent"); got != agent {
+			t.Errorf("%s User-Agent = %q, want %q", path, got, agent)
+		}
+	}
+	if got := seen["/release"].Get("Authorization"); got != "Bearer test-token" {
+		t.Errorf("API 请求 Authorization = %q", got)
+	}
+	if got := seen["/download"].Get("Authorization"); got != "" {
+		t.Errorf("下载请求不应附带 token, Authorization = %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+// 日志与错误信息的语言, 默认中文
+const (
+	langZH = "zh"
+	langEN = "en"
+)
+
+// lang 为当前使用的语言, 由 -lang 设置
+var lang = langZH
+
+// tr 返回 msg 在当前语言下的译文, 没有对应译文时原样返回
+// 译文以中文原文 (含格式化占位符) 作为键, 见 messages_en.go
+func tr(msg string) string {
+	if lang == langEN {
+		if s, ok := messagesEN[msg]; ok {
+			return s
+		}
+	}
+	return msg
+}
+
+// message 是错误信息会随 -lang 翻译的固定错误
+type message string
+
+func (m message) Error() string { return tr(string(m)) }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestMessagesEN 检查 messagesEN 与代码中的中文原文一致: 传给 tr / message 的字符串都应有译文,
+// 否则 -lang en 会输出中文; 每个键都应在代码中出现, 否则是改动代码后遗留的死条目
+// 部分原文 (如 fileStatusLabels) 先存入变量再传给 tr, 因此后者按代码中出现的所有字符串判断
+func TestMessagesEN(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := make(map[string]string)
+	literals := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") || name == "messages_en.go" {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			if lit, ok := n.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				if s, err := strconv.Unquote(lit.Value); err == nil {
+					literals[s] = true
+				}
+			}
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 1 {
+				return true
+			}
+			if fn, ok := call.Fun.(*ast.Ident); !ok || (fn.Name != "tr" && fn.Name != "message") {
+				return true
+			}
+			if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				if s, err := strconv.Unquote(lit.Value); err == nil {
+					used[s] = fset.Position(lit.Pos()).String()
+				}
+			}
+			return true
+		})
+	}
+	for msg, pos := range used {
+		if _, ok := messagesEN[msg]; !ok {
+			t.Errorf("%s: %q 没有英文译文", pos, msg)
+		}
+	}
+	for msg := range messagesEN {
+		if !literals[msg] {
+			t.Errorf("messagesEN 中的 %q 未被使用", msg)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stdinInput 为 -input 中表示从标准输入读取的取值
+const stdinInput = "-"
+
+// readInput 读取 -input 指定的本地后端文件, 返回文件名、内容和修改时间
+// 从标准输入读取时文件名视为 sub-store.bundle.js
+func readInput(path string) (string, []byte, time.Time, error) {
+	if path == stdinInput {
+		data, err := io.ReadAll(os.Stdin)
+		return "sub-store.bundle.js", data, time.Now(), err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	return filepath.Base(path), data, info.ModTime(), err
+}
+
+// updateFromInput 使用本地文件代替下载, 执行与 updateBackend 相同的压缩、替换与提交流程
+func updateFromInput(cfg *Config, st *State, destDir, gitDir string) (bool, error) {
+	name, data, modTime, err := readInput(cfg.Input)
+	if err != nil {
+		return false, fmt.Errorf(tr("读取 -input 文件失败: %w"), err)
+	}
+	log.Printf(tr("使用本地文件 %s (%s), 版本: %s"), cfg.Input, formatSize(int64(len(data))), cfg.Tag)
+
+	asset := &ReleaseAsset{Name: name, Size: int64(len(data)), CreatedAt: modTime, UpdatedAt: modTime}
+	a, err := backendArtifact(cfg, destDir, cfg.Tag, []assetData{{asset: asset, data: data}})
+	if err != nil {
+		return false, err
+	}
+	return publish(cfg, st, gitDir, a)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// interrupted 在收到 SIGINT / SIGTERM 后关闭
+var interrupted = make(chan struct{})
+
+// watchWaiting 为真表示守护模式正在等待下次检查, 此时的中断由 runWatch 处理并正常退出
+var watchWaiting atomic.Bool
+
+// tempFiles 记录运行中创建、中断时需要删除的临时文件和目录
+var tempFiles = struct {
+	sync.Mutex
+	paths map[string]bool
+}{paths: make(map[string]bool)}
+
+// trackTemp 登记临时文件, 进程被中断时删除
+func trackTemp(path string) {
+	tempFiles.Lock()
+	defer tempFiles.Unlock()
+	tempFiles.paths[path] = true
+}
+
+// untrackTemp 取消登记, 调用方负责删除或保留该文件
+func untrackTemp(path string) {
+	tempFiles.Lock()
+	defer tempFiles.Unlock()
+	delete(tempFiles.paths, path)
+}
+
+// removeTempFiles 删除所有已登记的临时文件
+func removeTempFiles() {
+	tempFiles.Lock()
+	defer tempFiles.Unlock()
+	for path := range tempFiles.paths {
+		if err := os.RemoveAll(path); err != nil {
+			log.Printf(tr("删除 %s 失败: %v"), path, err)
+		}
+		delete(tempFiles.paths, path)
+	}
+}
+
+// handleInterrupts 安装信号处理: 中断时删除临时文件并以 exitInterrupted 退出,
+// 避免在目标目录和工作目录中留下写了一半的文件; 守护模式等待期间的中断交由 runWatch 正常结束
+func handleInterrupts() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ch
+		close(interrupted)
+		if watchWaiting.Load() {
+			return
+		}
+		removeTempFiles()
+		log.Println(tr("收到中断信号, 已清理临时文件"))
+		os.Exit(exitInterrupted)
+	}()
+}
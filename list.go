@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"text/tabwriter"
+	"time"
+)
+
+// runList 列出后端与前端仓库最近 cfg.List 个 release, 便于选择要固定的版本
+func runList(cfg *Config) int {
+	repos := []struct{ name, repo string }{
+		{tr("后端"), "sub-store-org/Sub-Store"},
+		{tr("前端"), "sub-store-org/Sub-Store-Front-End"},
+	}
+	for _, r := range repos {
+		var releases []Release
+		err := cfg.Retry.do(fmt.Sprintf(tr("获取 %s release 列表"), r.repo), func() error {
+			var err error
+			releases, err = fetchReleases(r.repo, cfg.List)
+			return err
+		})
+		if err != nil {
+			log.Printf(tr("获取%s release 列表失败: %v"), r.name, err)
+			return exitCode(err)
+		}
+		log.Printf(tr("%s (%s) 最近 %d 个 release:\n%s"), r.name, r.repo, len(releases), releaseTable(releases))
+	}
+	return exitOK
+}
+
+// releaseTable 将 release 列表格式化为 tag / 发布时间 / 资源数 表格
+func releaseTable(releases []Release) string {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, tr("版本\t发布时间\t资源数\t"))
+	for _, r := range releases {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t\n", r.TagName, r.PublishedAt.Local().Format(time.DateTime), len(r.Assets))
+	}
+	tw.Flush()
+	return buf.String()
+}
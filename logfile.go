@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// rotatingLog 是按大小轮转的日志文件: 写入后超过 maxSize 时将 path 依次重命名为 path.1、path.2 ...,
+// 最多保留 keep 个旧文件, 更早的直接删除
+type rotatingLog struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	keep    int
+	f       *os.File
+	size    int64
+}
+
+// openRotatingLog 以追加方式打开日志文件, maxMB 为单个文件的大小上限 (MiB)
+func openRotatingLog(path string, maxMB int64, keep int) (*rotatingLog, error) {
+	l := &rotatingLog{path: path, maxSize: maxMB << 20, keep: keep}
+	if err := l.open(); err != nil {
+		return nil, fmt.Errorf(tr("打开日志文件失败: %w"), err)
+	}
+	return l, nil
+}
+
+func (l *rotatingLog) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f, l.size = f, info.Size()
+	return nil
+}
+
+// Write 写入一条日志, 写入前文件已满时先轮转; 单条日志不会被拆分到两个文件中
+func (l *rotatingLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(p)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			// 轮转失败时继续写入当前文件, 不丢失日志
+			fmt.Fprintf(os.Stderr, tr("日志文件轮转失败: %v")+"\n", err)
+		}
+	}
+	n, err := l.f.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// rotate 关闭当前文件, 依次后移旧文件后重新打开一个空文件
+func (l *rotatingLog) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	backup := func(i int) string { return l.path + "." + strconv.Itoa(i) }
+	if l.keep > 0 {
+		os.Remove(backup(l.keep))
+		for i := l.keep - 1; i >= 1; i-- {
+			os.Rename(backup(i), backup(i+1))
+		}
+		if err := os.Rename(l.path, backup(1)); err != nil {
+			l.open()
+			return err
+		}
+	} else if err := os.Remove(l.path); err != nil {
+		l.open()
+		return err
+	}
+	return l.open()
+}
+
+// Close 关闭日志文件
+func (l *rotatingLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
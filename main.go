@@ -6,237 +6,794 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/klauspost/compress/zstd"
 )
 
 type ReleaseAsset struct {
-	Name               string `json:"name"`
-	BrowserDownloadURL string `json:"browser_download_url"`
+	Name               string    `json:"name"`
+	Label              string    `json:"label"`
+	BrowserDownloadURL string    `json:"browser_download_url"`
+	Size               int64     `json:"size"`
+	Digest             string    `json:"digest"` // 如 sha256:..., 较早的 release 可能没有
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
 }
 
 type Release struct {
-	TagName string         `json:"tag_name"`
-	Assets  []ReleaseAsset `json:"assets"`
+	TagName     string         `json:"tag_name"`
+	PublishedAt time.Time      `json:"published_at"`
+	Assets      []ReleaseAsset `json:"assets"`
+	Immutable   bool           `json:"immutable"` // 不可变 release 发布后资源无法替换, 其摘要可作为可信校验依据
 }
 
 func fetchLatestRelease(repo string) (*Release, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", repo)
-	resp, err := http.Get(url)
+	return fetchRelease(fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", repo))
+}
+
+// fetchReleases 获取仓库最近的 n 个 release, 按发布时间从新到旧排列
+func fetchReleases(repo string, n int) ([]Release, error) {
+	req, err := newGitHubRequest(http.MethodGet, fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=%d", repo, min(n, 100)))
+	if err != nil {
+		return nil, err
+	}
+	acceptGzip(req)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API 请求失败: %s", resp.Status)
+		return nil, statusError(tr("GitHub API 请求失败"), resp)
+	}
+	body, err := responseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []Release
+	if err := json.NewDecoder(body).Decode(&releases); err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+// fetchReleaseByTag 获取指定 tag 对应的 release
+func fetchReleaseByTag(repo, tag string) (*Release, error) {
+	return fetchRelease(fmt.Sprintf("https://api.github.com/repos/%s/releases/tags/%s", repo, url.PathEscape(tag)))
+}
+
+func fetchRelease(url string) (*Release, error) {
+	req, err := newGitHubRequest(http.MethodGet, url)
+	if err != nil {
+		return nil, err
+	}
+	acceptGzip(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(tr("GitHub API 请求失败"), resp)
+	}
+	body, err := responseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if releaseDump.path != "" {
+		dumpRelease(url, raw)
 	}
 
 	var release Release
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+	if err := json.Unmarshal(raw, &release); err != nil {
 		return nil, err
 	}
 	return &release, nil
 }
 
-func downloadFile(url string) ([]byte, error) {
-	resp, err := http.Get(url)
+// releaseDump 记录 -dump-release 的输出文件和本次运行获取到的各 release 原始 JSON (按请求地址)
+var releaseDump = struct {
+	sync.Mutex
+	path     string
+	releases map[string]json.RawMessage
+}{releases: make(map[string]json.RawMessage)}
+
+// dumpRelease 将 API 返回的 release 原样写入 -dump-release, 便于排查资源选择失败等问题
+// 同一次运行会获取后端和前端等多个 release, 文件内容为以请求地址为键的 JSON 对象; 写入失败只记录日志
+func dumpRelease(url string, raw []byte) {
+	releaseDump.Lock()
+	defer releaseDump.Unlock()
+	if !json.Valid(raw) {
+		log.Printf(tr("警告: %s 返回的不是有效的 JSON, 未写入 -dump-release"), url)
+		return
+	}
+	releaseDump.releases[url] = slices.Clone(raw)
+	data, err := json.MarshalIndent(releaseDump.releases, "", "  ")
+	if err == nil {
+		err = os.WriteFile(releaseDump.path, append(data, '\n'), 0644)
+	}
 	if err != nil {
-		return nil, err
+		log.Printf(tr("警告: 写入 -dump-release %s 失败: %v"), releaseDump.path, err)
+	}
+}
+
+// maxRedirects 为下载时最多跟随的跳转次数, 可通过 -max-redirects 修改
+var maxRedirects = 10
+
+// logRedirects 为真时 (-verbose) 记录下载过程中的每次跳转, 便于排查来回转发请求的代理
+var logRedirects bool
+
+// downloadFile 下载 url 指向的文件, 同时返回跟随跳转后的最终地址
+// 响应体超过 limit 字节 (limit > 0 时) 时中止下载并返回错误
+// v 不为空时发送条件请求, 服务器返回 304 时返回 errNotModified, 下载成功时更新 v 中的 ETag / Last-Modified
+func downloadFile(url string, limit int64, v *validators) ([]byte, string, error) {
+	var lastHop string
+	client := &http.Client{
+		Transport: downloadTransport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			next := req.URL.String()
+			if logRedirects {
+				log.Printf(tr("跳转 %d: %s -> %s"), len(via), via[len(via)-1].URL, next)
+			}
+			// 循环跳转和超过次数上限都不会因重试而改变
+			for _, prev := range via {
+				if prev.URL.String() == next {
+					return permanent(fmt.Errorf(tr("检测到循环跳转: 再次跳转到 %s"), next))
+				}
+			}
+			if len(via) > maxRedirects {
+				return permanent(fmt.Errorf(tr("跳转次数超过 -max-redirects %d"), maxRedirects))
+			}
+			lastHop = next
+			return nil
+		},
+	}
+	req, err := newRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if v != nil {
+		if v.ETag != "" {
+			req.Header.Set("If-None-Match", v.ETag)
+		}
+		if v.LastModified != "" {
+			req.Header.Set("If-Modified-Since", v.LastModified)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if lastHop != "" {
+			return nil, "", fmt.Errorf(tr("跳转到 %s 后请求失败: %w"), lastHop, err)
+		}
+		return nil, "", err
 	}
 	defer resp.Body.Close()
-	return io.ReadAll(resp.Body)
+
+	finalURL := resp.Request.URL.String()
+	if resp.StatusCode == http.StatusNotModified && v != nil {
+		return nil, finalURL, permanent(errNotModified)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, finalURL, statusError(tr("下载请求失败"), resp)
+	}
+	if v != nil {
+		v.ETag = resp.Header.Get("ETag")
+		v.LastModified = resp.Header.Get("Last-Modified")
+	}
+	if limit > 0 && resp.ContentLength > limit {
+		return nil, finalURL, permanent(fmt.Errorf(tr("文件大小 %s 超过上限 %s"), formatSize(resp.ContentLength), formatSize(limit)))
+	}
+	var body io.Reader = resp.Body
+	if limit > 0 {
+		body = io.LimitReader(resp.Body, limit+1)
+	}
+	// 已知大小时一次性分配缓冲区, 避免 io.ReadAll 反复扩容带来的内存峰值
+	var buf bytes.Buffer
+	if resp.ContentLength > 0 {
+		buf.Grow(int(resp.ContentLength) + 1)
+	}
+	if _, err := buf.ReadFrom(body); err != nil {
+		return nil, finalURL, err
+	}
+	data := buf.Bytes()
+	if limit > 0 && int64(len(data)) > limit {
+		return nil, finalURL, permanent(fmt.Errorf(tr("响应内容超过上限 %s, 已中止下载"), formatSize(limit)))
+	}
+	return data, finalURL, nil
 }
 
-func compressZstd(data []byte) ([]byte, error) {
-	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+// mirrorURLs 根据镜像前缀生成备用下载地址
+// 镜像中包含 {url} 时替换为原始地址, 否则将原始地址拼接在镜像之后
+func mirrorURLs(mirrors []string, rawURL string) []string {
+	urls := make([]string, 0, len(mirrors))
+	for _, m := range mirrors {
+		if strings.Contains(m, "{url}") {
+			urls = append(urls, strings.ReplaceAll(m, "{url}", rawURL))
+		} else {
+			urls = append(urls, strings.TrimSuffix(m, "/")+"/"+rawURL)
+		}
+	}
+	return urls
+}
+
+// statusError 根据响应状态码生成错误, 4xx (429 除外) 视为不可重试
+// 速率限制返回 rateLimitError, 重试时等待到限额重置
+func statusError(desc string, resp *http.Response) error {
+	if err := rateLimited(desc, resp); err != nil {
+		return err
+	}
+	err := fmt.Errorf("%s: %s", desc, resp.Status)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return permanent(err)
+	}
+	return err
+}
+
+// latestRelease 按重试策略获取仓库的最新 release
+// 在 -cache-ttl 内重复运行时直接使用本地缓存
+func latestRelease(cfg *Config, repo string) (*Release, error) {
+	if release := cachedRelease(cfg, repo); release != nil {
+		return release, nil
+	}
+	var release *Release
+	err := cfg.Retry.do(fmt.Sprintf(tr("获取 %s release "), repo), func() error {
+		var err error
+		release, err = fetchLatestRelease(repo)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	storeRelease(cfg, repo, release)
+	return release, nil
+}
+
+// releaseByTag 按重试策略获取仓库指定 tag 的 release
+func releaseByTag(cfg *Config, repo, tag string) (*Release, error) {
+	var release *Release
+	err := cfg.Retry.do(fmt.Sprintf(tr("获取 %s release %s "), repo, tag), func() error {
+		var err error
+		release, err = fetchReleaseByTag(repo, tag)
+		return err
+	})
+	return release, err
+}
+
+// resolveRelease 确定组件本次要使用的 release
+// 配置了版本清单且其中列出该组件时使用清单批准的版本, 与已提交版本一致时返回 nil 表示无需更新;
+// 否则使用最新 release
+func resolveRelease(cfg *Config, repo, component, destDir string) (*Release, error) {
+	approved, ok := cfg.manifest[component]
+	if !ok {
+		return latestRelease(cfg, repo)
+	}
+	committed := committedTag(destDir, component)
+	log.Printf(tr("版本清单批准的 %s 版本: %s, 已提交版本: %s"), component, approved, committed)
+	if sameTag(committed, approved) {
+		log.Printf(tr("%s 已是清单批准的版本, 无需更新。"), component)
+		return nil, nil
+	}
+	return releaseByTag(cfg, repo, approved)
+}
+
+// findAsset 在 release 中按名称查找资源
+func findAsset(release *Release, name string) *ReleaseAsset {
+	for i := range release.Assets {
+		if release.Assets[i].Name == name {
+			return &release.Assets[i]
+		}
+	}
+	return nil
+}
+
+// findAssetBy 在 release 中查找指定字段 (name / label) 等于 value 的资源
+func findAssetBy(release *Release, field, value string) *ReleaseAsset {
+	for i := range release.Assets {
+		if release.Assets[i].field(field) == value {
+			return &release.Assets[i]
+		}
+	}
+	return nil
+}
+
+// field 返回用于匹配的资源字段, label 以外的取值均使用文件名
+func (a *ReleaseAsset) field(name string) string {
+	if name == matchLabel {
+		return a.Label
+	}
+	return a.Name
+}
+
+// logAssetInfo 输出 API 返回的资源大小和时间信息
+func logAssetInfo(asset *ReleaseAsset) {
+	log.Printf(tr("资源大小: %s, 创建于: %s, 更新于: %s"),
+		formatSize(asset.Size),
+		asset.CreatedAt.Local().Format(time.DateTime),
+		asset.UpdatedAt.Local().Format(time.DateTime))
+}
+
+// streamThreshold 为改用流式压缩的输入大小
+// EncodeAll 需要按输入大小预先分配输出缓冲区, 较大的输入改为流式写入, 缓冲区随输出增长
+// 只有流式压缩能利用多个 goroutine, 较小的输入单线程压缩已足够快
+const streamThreshold = 16 << 20
+
+// compressZstd 压缩 data, 达到 streamThreshold 时以 threads 个 goroutine 流式压缩
+func compressZstd(data []byte, level zstd.EncoderLevel, threads int) ([]byte, error) {
+	if len(data) >= streamThreshold {
+		var buf bytes.Buffer
+		if err := compressZstdStream(&buf, bytes.NewReader(data), level, threads); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(level))
 	if err != nil {
 		return nil, err
 	}
 	return encoder.EncodeAll(data, make([]byte, 0, len(data))), nil
 }
 
+// compressZstdStream 以流式方式将 src 压缩写入 dst, 不需要一次性缓冲全部输入或输出
+// 流式压缩的输出与 threads 无关, 调整线程数不会导致文件内容变化
+func compressZstdStream(dst io.Writer, src io.Reader, level zstd.EncoderLevel, threads int) error {
+	encoder, err := zstd.NewWriter(dst, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(threads))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(encoder, src); err != nil {
+		encoder.Close()
+		return err
+	}
+	return encoder.Close()
+}
+
+// errChecksumMismatch 表示下载内容与期望的 sha256 不一致, 可能是 CDN 返回了截断的内容, 重新下载可能成功
+var errChecksumMismatch = message("校验和不匹配")
+
+// verifyHash 校验数据的 sha256 是否与期望值一致
+func verifyHash(data, expected []byte) error {
+	sum := sha256.Sum256(data)
+	if !bytes.Equal(sum[:], expected) {
+		return withKind(errChecksumMismatch, fmt.Errorf(tr("sha256 不匹配: 期望 %x, 实际 %x"), expected, sum))
+	}
+	return nil
+}
+
+// fileHash 计算文件在 -hash-algo 指定算法下的摘要
 func fileHash(path string) ([]byte, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
-	h := sha256.New()
+	h := newHash()
 	if _, err := io.Copy(h, f); err != nil {
 		return nil, err
 	}
 	return h.Sum(nil), nil
 }
 
-func runGitCommands(relPath string, tag string, push bool, component string) error {
-	commitMsg := fmt.Sprintf("chore(%s): update to %s", component, tag)
-	cmds := []struct {
-		args []string
-		desc string
-	}{
-		{[]string{"git", "add", relPath}, "git 添加"},
-		{[]string{"git", "commit", "-m", commitMsg}, "git 提交"},
+// publishedAfterSince 检查 release 是否晚于 -since 指定的时间发布
+func publishedAfterSince(cfg *Config, release *Release, name string) bool {
+	if cfg.Since.IsZero() {
+		return true
 	}
-	if push {
-		cmds = append(cmds, struct {
-			args []string
-			desc string
-		}{[]string{"git", "push", "origin", "main"}, "git 推送"})
+	if release.PublishedAt.After(cfg.Since) {
+		return true
 	}
+	log.Printf(tr("%s版本 %s 发布于 %s, 早于 -since %s, 跳过更新"), name, release.TagName,
+		release.PublishedAt.Local().Format(time.DateTime), cfg.Since.Local().Format(time.DateTime))
+	return false
+}
 
-	for _, cmd := range cmds {
-		out, err := exec.Command(cmd.args[0], cmd.args[1:]...).CombinedOutput()
-		if err != nil {
-			return fmt.Errorf("%s 失败: %v\n输出: %s", cmd.desc, err, out)
+// versionedUpToDate 在 -path-template 按版本区分路径时判断组件是否无需更新: 该版本的文件已存在
+// 不使用模板时新版本会覆盖同一路径, 仍按文件内容判断是否需要更新
+func versionedUpToDate(cfg *Config, component, destDir string, release *Release) bool {
+	if cfg.pathTmpl == nil || cfg.Output != "" || cfg.SyncMetadata || !versionPresent(destDir, component, release.TagName) {
+		return false
+	}
+	log.Printf(tr("%s %s 的文件已存在, 无需更新。"), component, release.TagName)
+	return true
+}
+
+// unchangedSinceLastRun 在 -since-last-run 下判断组件是否无需更新:
+// release 与资源都早于该组件上次成功运行, 且目标目录中已是该版本
+// 只比较时间和 tag, 不需要服务器支持 ETag, 结果偏保守
+func unchangedSinceLastRun(cfg *Config, st *State, component, destDir string, release *Release, assets ...*ReleaseAsset) bool {
+	last, ok := st.LastRun[component]
+	if !cfg.SinceLastRun || !ok || cfg.Output != "" || !sameTag(committedTag(destDir, component), release.TagName) {
+		return false
+	}
+	newest := release.PublishedAt
+	for _, a := range assets {
+		if a.UpdatedAt.After(newest) {
+			newest = a.UpdatedAt
 		}
 	}
+	if newest.After(last) {
+		return false
+	}
+	log.Printf(tr("%s %s 及其资源早于上次成功运行 (%s), 跳过下载 (-since-last-run)"), component, release.TagName, last.Local().Format(time.DateTime))
+	return true
+}
 
-	log.Printf("成功更新 %s 到 %s", component, tag)
-	if push {
-		log.Println("已完成 git 提交和远程仓库推送")
-	} else {
-		log.Println("已完成 git 提交, 请手动推送到远程仓库")
+// recordRun 在 -since-last-run 下记录组件本次成功运行的时间
+func recordRun(cfg *Config, st *State, component string, start time.Time) {
+	if !cfg.SinceLastRun || cfg.DryRun || cfg.Output != "" {
+		return
+	}
+	st.LastRun[component] = start
+	if err := st.save(cfg.StateFile); err != nil {
+		log.Printf(tr("保存状态文件失败: %v"), err)
 	}
-	return nil
 }
 
-func updateBackend(destDir, gitDir string, push bool) {
-	release, err := fetchLatestRelease("sub-store-org/Sub-Store")
-	if err != nil {
-		log.Fatalf("获取后端 release 失败: %v", err)
+// downloadSource 记录资源内容的实际来源, 写入元数据供审计
+type downloadSource struct {
+	finalURL string // 跟随跳转后的最终地址, 已去掉查询参数
+	mirror   string // 使用的 -mirror 镜像, 使用原始地址时为空
+	cached   bool   // 内容来自下载缓存
+}
+
+// downloadAsset 下载资源并与 API 声明的大小进行比对,
+// 原始地址失败时依次尝试配置的镜像地址
+// expected 不为空时每次下载后都校验 sha256, 不匹配的内容视为下载失败并重试, 只接受校验通过的内容
+func downloadAsset(cfg *Config, name string, asset *ReleaseAsset, expected []byte) ([]byte, downloadSource, error) {
+	// 下载前按 API 声明的大小检查, 资源选择匹配到意外的大文件时不浪费流量
+	if limit := cfg.MaxAssetMB << 20; limit > 0 && asset.Size > limit {
+		return nil, downloadSource{}, withKind(ErrDownloadFailed, fmt.Errorf(tr("%s %s 大小为 %s, 超过 -max-asset-size %d MiB, 请检查资源选择是否正确"), name, asset.Name, formatSize(asset.Size), cfg.MaxAssetMB))
 	}
+	if data := cachedDownload(cfg, asset); data != nil {
+		log.Printf(tr("使用下载缓存中的%s文件: %s"), name, formatSize(int64(len(data))))
+		return data, downloadSource{cached: true}, nil
+	}
+	// API 未提供摘要时, 以上次下载记录的 ETag / Last-Modified 发送条件请求
+	cond, cached := conditionalDownload(cfg, asset)
+	urls := append([]string{asset.BrowserDownloadURL}, mirrorURLs(cfg.Mirrors, asset.BrowserDownloadURL)...)
 
-	var downloadURL string
-	for _, asset := range release.Assets {
-		if asset.Name == "sub-store.bundle.js" {
-			downloadURL = asset.BrowserDownloadURL
+	var (
+		data     []byte
+		finalURL string
+		mirror   string
+		errs     []error
+	)
+	for i := 0; i < len(urls); i++ {
+		u := urls[i]
+		if i > 0 {
+			log.Printf(tr("尝试镜像地址: %s"), u)
+		}
+		err := cfg.Retry.do(fmt.Sprintf(tr("下载%s文件"), name), func() error {
+			var err error
+			data, finalURL, err = downloadFile(u, cfg.MaxDownloadMB<<20, cond)
+			if err == nil && expected != nil {
+				err = verifyHash(data, expected)
+			}
+			return err
+		})
+		if errors.Is(err, errNotModified) {
+			if expected == nil || verifyHash(cached, expected) == nil {
+				log.Printf(tr("%s文件未变化 (304), 使用下载缓存: %s"), name, formatSize(int64(len(cached))))
+				return cached, downloadSource{cached: true}, nil
+			}
+			// 缓存内容与期望的摘要不符时不能信任 304, 删除缓存后对同一地址发送不带条件头的请求
+			if cond.ETag != "" || cond.LastModified != "" {
+				log.Printf(tr("下载缓存中的%s文件与期望的校验和不符, 已删除缓存并重新下载"), name)
+				dropValidators(cfg, asset, cond)
+				cond, cached = &validators{}, nil
+				i--
+				continue
+			}
+			err = fmt.Errorf(tr("%s 未发送条件头却返回 304"), u)
+		}
+		if err == nil {
+			if i > 0 {
+				mirror = cfg.Mirrors[i-1]
+			}
+			errs = nil
 			break
 		}
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		err := errors.Join(errs...)
+		if !slices.ContainsFunc(errs, func(err error) bool { return !errors.Is(err, errChecksumMismatch) }) {
+			err = fmt.Errorf(tr("%s文件的校验和始终不匹配, 已尝试 %d 个下载地址: %w"), name, len(urls), err)
+		}
+		return nil, downloadSource{}, withKind(ErrDownloadFailed, fmt.Errorf(tr("下载%s文件失败: %w"), name, err))
+	}
+	if expected != nil {
+		log.Printf(tr("%s 校验通过"), asset.Name)
+	}
+	if finalURL != asset.BrowserDownloadURL {
+		log.Println(tr("实际下载地址:"), finalURL)
 	}
-	if downloadURL == "" {
-		log.Fatal("未找到 sub-store.bundle.js")
+	log.Printf(tr("已下载%s文件: %s"), name, formatSize(int64(len(data))))
+	if asset.Size > 0 && int64(len(data)) != asset.Size {
+		log.Printf(tr("警告: 下载大小 %d 字节与 API 声明的 %d 字节不一致"), len(data), asset.Size)
 	}
+	storeDownload(cfg, data)
+	storeValidators(cfg, asset, cond, data)
+	return data, downloadSource{finalURL: auditURL(finalURL), mirror: auditURL(mirror)}, nil
+}
 
-	log.Println("后端最新版本:", release.TagName)
-	log.Println("下载地址:", downloadURL)
+// auditURL 去掉地址中的认证信息和查询参数 (如会过期的下载签名), 用于写入元数据
+func auditURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return raw
+	}
+	u.User, u.RawQuery, u.Fragment = nil, "", ""
+	return u.String()
+}
 
-	jsData, err := downloadFile(downloadURL)
+func updateBackend(cfg *Config, st *State, destDir, gitDir string) (bool, error) {
+	start := time.Now()
+	release, err := resolveRelease(cfg, "sub-store-org/Sub-Store", "sub-store", destDir)
 	if err != nil {
-		log.Fatalf("下载后端文件失败: %v", err)
+		return false, fmt.Errorf(tr("获取后端 release 失败: %w"), err)
+	}
+	if release == nil {
+		return false, nil
 	}
 
-	compressed, err := compressZstd(jsData)
+	var assets []*ReleaseAsset
+	release, err = waitForAsset(cfg, "sub-store-org/Sub-Store", release, func(r *Release) error {
+		var err error
+		assets, err = backendAssets(cfg, r)
+		return err
+	})
 	if err != nil {
-		log.Fatalf("压缩后端文件失败: %v", err)
+		return false, err
 	}
 
-	destPath := filepath.Join(destDir, "sub-store.bundle.js.zst")
-	currentHash, err := fileHash(destPath)
-	if err != nil && !os.IsNotExist(err) {
-		log.Printf("无法计算当前后端文件哈希: %v", err)
+	log.Println(tr("后端最新版本:"), release.TagName)
+	if !publishedAfterSince(cfg, release, tr("后端")) {
+		return false, nil
+	}
+	if unchangedSinceLastRun(cfg, st, "sub-store", destDir, release, assets...) {
+		return false, nil
+	}
+	if versionedUpToDate(cfg, "sub-store", destDir, release) {
+		return false, nil
+	}
+	for _, asset := range assets {
+		log.Println(tr("下载地址:"), asset.BrowserDownloadURL)
+		logAssetInfo(asset)
 	}
 
-	newHash := sha256.Sum256(compressed)
+	// 资源摘要与上次生成目标文件时相同, 且目标目录仍是该版本、文件未被删除或改动时, 跳过下载和压缩
+	// 文件缺失或损坏 (如被替换为 HTML 错误页) 时照常生成, 由 publish 强制替换
+	key := sourceKey(cfg, assets)
+	if key != "" && !cfg.NoCache && !cfg.SyncMetadata && cfg.Output == "" && cfg.Compressor == nil &&
+		st.Sources["sub-store"] == key && sameTag(committedTag(destDir, "sub-store"), release.TagName) &&
+		outputsIntact(destDir, "sub-store", release.TagName) {
+		log.Println(tr("后端资源摘要与上次相同, 跳过下载和压缩。"))
+		return false, nil
+	}
 
-	if bytes.Equal(currentHash, newHash[:]) {
-		log.Println("后端文件已是最新，无需更新。")
-		return
+	a, err := buildBackend(cfg, destDir, release, assets)
+	if err != nil {
+		return false, err
+	}
+	updated, err := publish(cfg, st, gitDir, a)
+	if err == nil {
+		recordRun(cfg, st, "sub-store", start)
+	}
+	if err == nil && key != "" && !cfg.DryRun && cfg.Output == "" && len(a.changedFiles()) == 0 {
+		// 只在目标文件确已与本次生成结果一致时记录, 因提交间隔推迟的更新不记录
+		st.Sources["sub-store"] = key
+		if err := st.save(cfg.StateFile); err != nil {
+			log.Printf(tr("保存状态文件失败: %v"), err)
+		}
 	}
+	return updated, err
+}
 
-	log.Println("后端文件有更新，准备替换...")
-	if err := os.WriteFile(destPath, compressed, 0644); err != nil {
-		log.Fatalf("写入后端文件失败: %v", err)
+// buildBackend 下载后端资源并按 -format 生成待写入目标目录的产物
+func buildBackend(cfg *Config, destDir string, release *Release, assets []*ReleaseAsset) (*artifact, error) {
+	downloads, err := downloadAssets(cfg, tr("后端"), release, assets)
+	if err != nil {
+		return nil, fmt.Errorf(tr("下载后端文件失败: %w"), err)
 	}
-	log.Println("已将后端压缩文件更新到:", destPath)
+	return backendArtifact(cfg, destDir, release.TagName, downloads)
+}
 
-	originalWd, _ := os.Getwd()
-	if err := os.Chdir(gitDir); err != nil {
-		log.Fatalf("切换到 git 目录失败: %v", err)
+// backendArtifact 将已获取的后端资源解压、按 -format 压缩, 生成待写入目标目录的产物
+func backendArtifact(cfg *Config, destDir, tag string, downloads []assetData) (*artifact, error) {
+	a := &artifact{
+		component: "sub-store",
+		name:      tr("后端"),
+		tag:       tag,
 	}
-	defer os.Chdir(originalWd)
+	outputs := make(map[string]*ReleaseAsset)
+	for _, d := range downloads {
+		name, raw, err := decompressInput(d.asset.Name, d.data, cfg.InputCompressions, cfg.MaxDownloadMB<<20)
+		if err != nil {
+			return nil, err
+		}
+		// 同一文件同时以原始和压缩格式发布时只保留先匹配到的一份
+		if prev, ok := outputs[name]; ok {
+			log.Printf(tr("%s 与 %s 解压后同名, 已忽略 %s"), d.asset.Name, prev.Name, d.asset.Name)
+			continue
+		}
+		outputs[name] = d.asset
 
-	relPath, _ := filepath.Rel(gitDir, destPath)
-	if err := runGitCommands(relPath, release.TagName, push, "sub-store"); err != nil {
-		log.Fatalf("后端 git 操作失败: %v", err)
+		jsPath, err := cfg.outputPath(destDir, a.component, tag, name)
+		if err != nil {
+			return nil, err
+		}
+		// 不匹配 -compress-pattern 的附带文件 (如 version.txt) 原样提交
+		if !cfg.compressible(name) {
+			a.files = append(a.files, outputFile{path: jsPath, data: raw, asset: d.asset, source: d.source})
+			continue
+		}
+		if cfg.Format == formatJS || cfg.Format == formatBoth {
+			a.files = append(a.files, outputFile{path: jsPath, data: raw, asset: d.asset, source: d.source})
+		}
+		if cfg.Format == formatZst || cfg.Format == formatBoth {
+			c := cfg.compressor()
+			compressed, err := c.Compress(raw)
+			if err != nil {
+				return nil, fmt.Errorf(tr("压缩后端文件失败: %w"), err)
+			}
+			log.Printf(tr("%s 压缩后大小: %s"), name, formatSize(int64(len(compressed))))
+			zstPath, err := cfg.outputPath(destDir, a.component, tag, name+c.Ext())
+			if err != nil {
+				return nil, err
+			}
+			a.files = append(a.files, outputFile{path: zstPath, data: compressed, asset: d.asset, source: d.source})
+		}
 	}
+	return a, nil
 }
 
-func updateFrontend(destDir, gitDir string, push bool) {
-	release, err := fetchLatestRelease("sub-store-org/Sub-Store-Front-End")
+func updateFrontend(cfg *Config, st *State, destDir, gitDir string) (bool, error) {
+	start := time.Now()
+	release, err := resolveRelease(cfg, "sub-store-org/Sub-Store-Front-End", "sub-store-frontend", destDir)
 	if err != nil {
-		log.Fatalf("获取前端 release 失败: %v", err)
+		return false, fmt.Errorf(tr("获取前端 release 失败: %w"), err)
+	}
+	if release == nil {
+		return false, nil
 	}
 
-	var downloadURL string
-	for _, asset := range release.Assets {
-		if asset.Name == "dist.zip" {
-			downloadURL = asset.BrowserDownloadURL
-			break
+	var asset *ReleaseAsset
+	release, err = waitForAsset(cfg, "sub-store-org/Sub-Store-Front-End", release, func(r *Release) error {
+		if asset = findAssetBy(r, cfg.MatchField, "dist.zip"); asset == nil {
+			return withKind(ErrAssetNotFound, errors.New(tr("未找到 dist.zip")))
 		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	log.Println(tr("前端最新版本:"), release.TagName)
+	if !publishedAfterSince(cfg, release, tr("前端")) {
+		return false, nil
 	}
-	if downloadURL == "" {
-		log.Fatal("未找到 dist.zip")
+	if unchangedSinceLastRun(cfg, st, "sub-store-frontend", destDir, release, asset) {
+		return false, nil
 	}
+	if versionedUpToDate(cfg, "sub-store-frontend", destDir, release) {
+		return false, nil
+	}
+	log.Println(tr("下载地址:"), asset.BrowserDownloadURL)
+	logAssetInfo(asset)
 
-	log.Println("前端最新版本:", release.TagName)
-	log.Println("下载地址:", downloadURL)
+	a, err := buildFrontend(cfg, destDir, release, asset)
+	if err != nil {
+		return false, err
+	}
+	updated, err := publish(cfg, st, gitDir, a)
+	if err == nil {
+		recordRun(cfg, st, "sub-store-frontend", start)
+	}
+	return updated, err
+}
 
-	zipData, err := downloadFile(downloadURL)
+// buildFrontend 下载前端 dist.zip 并重新打包, 生成待写入目标目录的产物
+func buildFrontend(cfg *Config, destDir string, release *Release, asset *ReleaseAsset) (*artifact, error) {
+	expected, err := expectedChecksum(cfg, release, asset)
 	if err != nil {
-		log.Fatalf("下载前端文件失败: %v", err)
+		return nil, fmt.Errorf(tr("校验前端文件失败: %w"), err)
+	}
+	zipData, source, err := downloadAsset(cfg, tr("前端"), asset, expected)
+	if err == nil {
+		err = verifyAssetSignature(cfg, release, asset, zipData)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	tmpDir := "dist_temp"
+	tarData, err := buildFrontendArchive(zipData, cfg.encoderLevel(), cfg.CompressThreads)
+	if err != nil {
+		return nil, err
+	}
+	tarPath, err := cfg.outputPath(destDir, "sub-store-frontend", release.TagName, "sub-store.frontend.tar.zst")
+	if err != nil {
+		return nil, err
+	}
+	return &artifact{
+		component: "sub-store-frontend",
+		name:      tr("前端"),
+		tag:       release.TagName,
+		files:     []outputFile{{path: tarPath, data: tarData, asset: asset, source: source}},
+	}, nil
+}
+
+// buildFrontendArchive 解压 dist.zip 并重新打包为 tar.zst
+func buildFrontendArchive(zipData []byte, level zstd.EncoderLevel, threads int) ([]byte, error) {
+	tmpDir, _ := filepath.Abs("dist_temp")
 	os.RemoveAll(tmpDir)
-	defer os.RemoveAll(tmpDir)
+	trackTemp(tmpDir)
+	defer func() {
+		os.RemoveAll(tmpDir)
+		untrackTemp(tmpDir)
+	}()
 
 	zipReader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
 	if err != nil {
-		log.Fatalf("创建 zip reader 失败: %v", err)
+		return nil, fmt.Errorf(tr("创建 zip reader 失败: %w"), err)
 	}
 
+	// 归档中的时间和权限取自 zip 条目而不是解压出的文件, 相同的 dist.zip 总是生成相同的字节,
+	// 否则每次运行都会因 mtime 不同被视为有更新
+	entries := make(map[string]*zip.File)
 	for _, f := range zipReader.File {
 		fpath := filepath.Join(tmpDir, f.Name)
 		if !strings.HasPrefix(fpath, filepath.Clean(tmpDir)+string(os.PathSeparator)) {
-			log.Fatalf("非法文件路径: %s", fpath)
+			return nil, fmt.Errorf(tr("非法文件路径: %s"), fpath)
 		}
+		entries[filepath.Clean(fpath)] = f
 		if f.FileInfo().IsDir() {
 			os.MkdirAll(fpath, os.ModePerm)
 			continue
 		}
 		if err = os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
-			log.Fatalf("创建目录失败: %v", err)
+			return nil, fmt.Errorf(tr("创建目录失败: %w"), err)
 		}
 		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
 		if err != nil {
-			log.Fatalf("创建文件失败: %v", err)
+			return nil, fmt.Errorf(tr("创建文件失败: %w"), err)
 		}
 		rc, err := f.Open()
 		if err != nil {
 			outFile.Close()
-			log.Fatalf("打开 zip 内文件失败: %v", err)
+			return nil, fmt.Errorf(tr("打开 zip 内文件失败: %w"), err)
 		}
 		_, err = io.Copy(outFile, rc)
 		outFile.Close()
 		rc.Close()
 		if err != nil {
-			log.Fatalf("解压文件失败: %v", err)
+			return nil, fmt.Errorf(tr("解压文件失败: %w"), err)
 		}
 	}
 
 	var tarZstBuf bytes.Buffer
-	zstdEncoder, err := zstd.NewWriter(&tarZstBuf, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	zstdEncoder, err := zstd.NewWriter(&tarZstBuf, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(threads))
 	if err != nil {
-		log.Fatalf("创建 zstd writer 失败: %v", err)
+		return nil, fmt.Errorf(tr("创建 zstd writer 失败: %w"), err)
 	}
 	tw := tar.NewWriter(zstdEncoder)
 	srcDir := filepath.Join(tmpDir, "dist")
@@ -256,6 +813,7 @@ func updateFrontend(destDir, gitDir string, push bool) {
 			return err
 		}
 		hdr.Name = relPath
+		normalizeTarHeader(hdr, entries[filepath.Clean(path)])
 		if err := tw.WriteHeader(hdr); err != nil {
 			return err
 		}
@@ -273,75 +831,224 @@ func updateFrontend(destDir, gitDir string, push bool) {
 	tw.Close()
 	zstdEncoder.Close()
 
-	tarData := tarZstBuf.Bytes()
-	destPath := filepath.Join(destDir, "sub-store.frontend.tar.zst")
+	return tarZstBuf.Bytes(), nil
+}
 
-	currentHash, err := fileHash(destPath)
-	if err != nil && !os.IsNotExist(err) {
-		log.Printf("无法计算当前前端文件哈希: %v", err)
+// normalizeTarHeader 去掉 tar 头中与解压环境有关的信息: 修改时间取自 zip 条目 (zip 中没有的目录为零值),
+// 权限取自 zip 条目 (目录默认 0755), 不记录属主和访问时间
+func normalizeTarHeader(hdr *tar.Header, entry *zip.File) {
+	hdr.ModTime, hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}, time.Time{}
+	hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+	if hdr.Typeflag == tar.TypeDir {
+		hdr.Mode = 0755
 	}
+	if entry != nil {
+		hdr.ModTime = entry.Modified.UTC().Truncate(time.Second)
+		if perm := entry.Mode().Perm(); perm != 0 {
+			hdr.Mode = int64(perm)
+		}
+	}
+}
 
-	newHash := sha256.Sum256(tarData)
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	if bytes.Equal(currentHash, newHash[:]) {
-		log.Println("前端文件已是最新，无需更新。")
-		return
+// run 执行一次完整的检查与更新流程，返回进程退出码
+func run(args []string) int {
+	cfg, err := parseFlags(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
 	}
 
-	log.Println("前端文件有更新，准备替换...")
-	if err := os.WriteFile(destPath, tarData, 0644); err != nil {
-		log.Fatalf("写入前端文件失败: %v", err)
+	if cfg.ShowVersion {
+		fmt.Println(versionString())
+		return exitOK
+	}
+	if cfg.LogFile != "" {
+		w, err := openRotatingLog(cfg.LogFile, cfg.LogMaxMB, cfg.LogKeep)
+		if err != nil {
+			log.Println(err)
+			return exitError
+		}
+		defer w.Close()
+		log.SetOutput(w)
+	}
+	handleInterrupts()
+	if cfg.Report != "" {
+		log.SetOutput(reportLogWriter{log.Writer()})
+	}
+	if err := configureTLS(cfg.CACert, cfg.InsecureTLS); err != nil {
+		log.Println(err)
+		return exitError
+	}
+	userAgent = cfg.UserAgent
+	githubToken = cfg.Token
+	if cfg.AppID != "" {
+		app, err := loadAppAuth(cfg)
+		if err != nil {
+			log.Println(err)
+			return exitError
+		}
+		githubApp = app
+	}
+	hashAlgo = cfg.HashAlgo
+	proxyProbeAttempts = cfg.ProxyAttempts
+	proxyLatencySamples = cfg.ProxySamples
+	maxRedirects, logRedirects = cfg.MaxRedirects, cfg.Verbose
+	releaseDump.path = cfg.DumpRelease
+	if len(cfg.ProxyTestTargets) > 0 {
+		proxyTestTargets = cfg.ProxyTestTargets
+	}
+
+	destDir := cfg.DestDir
+	gitDir := cfg.repoPath()
+
+	if cfg.Command == "config" {
+		return runPrintConfig(cfg)
+	}
+	if cfg.Command == "clean" {
+		return runClean(cfg, destDir)
+	}
+	if cfg.Command == "list-proxies" {
+		return runListProxies(cfg)
+	}
+	if cfg.Command == "self-update" {
+		if _, err := setupProxy(cfg); err != nil {
+			log.Println(err)
+			return exitCode(err)
+		}
+		return runSelfUpdate(cfg)
 	}
-	log.Println("已将前端 tar 文件更新到:", destPath)
 
-	originalWd, _ := os.Getwd()
-	if err := os.Chdir(gitDir); err != nil {
-		log.Fatalf("切换到 git 目录失败: %v", err)
+	st, err := loadState(cfg.StateFile)
+	if err != nil {
+		log.Printf(tr("读取状态文件失败: %v"), err)
+		return exitError
 	}
-	defer os.Chdir(originalWd)
 
-	relPath, _ := filepath.Rel(gitDir, destPath)
-	if err := runGitCommands(relPath, release.TagName, push, "sub-store-frontend"); err != nil {
-		log.Fatalf("前端 git 操作失败: %v", err)
+	if !cfg.NoCommit && (!cfg.DryRun || cfg.DryRunGit) {
+		if isBareRepo(gitDir) {
+			// 裸仓库没有检出的分支, 提交时在 -branch 的工作树中进行
+			log.Printf(tr("%s 为裸仓库, 将在 %s 分支的工作树中提交"), gitDir, cfg.Branch)
+		} else {
+			if err := ensureGitRepo(gitDir); err != nil {
+				log.Println(err)
+				return exitCode(err)
+			}
+			// -dry-run-git 只检查分支, 不自动切换
+			if err := ensureBranch(gitDir, cfg.Branch, cfg.CheckoutBranch && !cfg.DryRun); err != nil {
+				log.Println(err)
+				return exitCode(err)
+			}
+		}
+		if !cfg.DryRun && cfg.Push && cfg.RetryPush {
+			if err := retryPendingPush(cfg.pushPolicy(), gitDir, cfg.Branch); err != nil {
+				log.Printf(tr("重新推送未推送的提交失败: %v"), err)
+			}
+		}
+	}
+	if cfg.Command == "commit-only" {
+		return runCommitOnly(cfg, st, gitDir)
+	}
+	if !cfg.DryRun && cfg.Output == "" {
+		if err := os.MkdirAll(destDir, 0755); err != nil {
+			log.Printf(tr("创建目标目录失败: %v"), err)
+			return exitError
+		}
 	}
-}
 
-func main() {
-	push := false
-	for _, arg := range os.Args[1:] {
-		if arg == "--push" || arg == "-p" {
-			push = true
-			break
+	if cfg.Input != "" {
+		startReport(cfg, "")
+		pending, err := updateFromInput(cfg, st, destDir, gitDir)
+		writeReport(cfg, err)
+		if err != nil {
+			log.Println(err)
+			return exitCode(err)
 		}
+		return resultCode(cfg, pending)
 	}
 
-	commonProxies := []string{
-		"http://127.0.0.1:7890",
-		"http://127.0.0.1:7891",
-		"http://127.0.0.1:1080",
-		"http://127.0.0.1:8080",
-		"http://127.0.0.1:10808",
-		"http://127.0.0.1:10809",
+	proxy, err := setupProxy(cfg)
+	if err != nil {
+		log.Println(err)
+		return exitCode(err)
+	}
+
+	if cfg.Token != "" || githubApp != nil {
+		if err := validateToken(cfg, gitDir); err != nil {
+			log.Println(err)
+			return exitCode(err)
+		}
+		if cfg.Verbose {
+			log.Println(tr("GitHub token 校验通过"))
+		}
 	}
 
-	proxy := findAvailableProxy("http://127.0.0.1:10808", commonProxies)
-	if proxy != "" {
-		os.Setenv("HTTP_PROXY", proxy)
-		os.Setenv("HTTPS_PROXY", proxy)
-		log.Println("使用代理:", proxy)
-	} else {
-		log.Println("未找到可用代理，将不设置代理")
+	if cfg.List > 0 {
+		return runList(cfg)
+	}
+	if cfg.Command == "verify" {
+		return runVerify(cfg, destDir)
+	}
+	// -once 覆盖配置文件中的 -watch, 只运行一次检查
+	watch := cfg.Watch > 0 && !cfg.Once
+	if cfg.MetricsAddr != "" && !watch {
+		log.Println(tr("-metrics-addr 只在守护模式 (-watch) 下生效, 已忽略"))
+	}
+	if watch {
+		return runWatch(cfg, st, destDir, gitDir, proxy)
 	}
+	startReport(cfg, proxy)
+	pending, err := runOnce(cfg, st, destDir, gitDir)
+	writeReport(cfg, err)
+	if err != nil {
+		log.Println(err)
+		return exitCode(err)
+	}
+	return resultCode(cfg, pending)
+}
 
-	destDir := `d:\Desktop\GoWork\subs-check-pro\assets`
-	gitDir := filepath.Dir(destDir)
+// resultCode 返回一次成功运行的退出码: 检查模式下有可用更新时为 3,
+// 没有任何更新且指定了 -fail-on-no-update 时为 9, 其余为 0
+func resultCode(cfg *Config, pending bool) int {
+	switch {
+	case cfg.DryRun && pending:
+		return exitUpdateAvailable
+	case !pending && cfg.FailOnNoUpdate:
+		log.Println(tr("没有可用更新, 按 -fail-on-no-update 以非零退出码退出"))
+		return exitNoUpdate
+	}
+	return exitOK
+}
 
-	if err := os.MkdirAll(destDir, 0755); err != nil {
-		log.Fatalf("创建目标目录失败: %v", err)
+// runOnce 检查并更新所有组件一次, 返回是否存在可用更新
+func runOnce(cfg *Config, st *State, destDir, gitDir string) (bool, error) {
+	if cfg.ManifestURL != "" {
+		manifest, err := loadManifest(cfg)
+		if err != nil {
+			return false, fmt.Errorf(tr("获取版本清单失败: %w"), err)
+		}
+		cfg.manifest = manifest
 	}
 
-	updateBackend(destDir, gitDir, push)
-	updateFrontend(destDir, gitDir, push)
+	pending := false
+	updaters := []func(*Config, *State, string, string) (bool, error){updateBackend, updateFrontend}
+	if cfg.Output == stdoutOutput {
+		// 标准输出只输出后端文件
+		updaters = updaters[:1]
+	}
+	for _, update := range updaters {
+		updated, err := update(cfg, st, destDir, gitDir)
+		if err != nil {
+			return pending, err
+		}
+		pending = pending || updated
+	}
 
-	log.Println("--- 所有检查已完成 ---")
+	log.Println(tr("--- 所有检查已完成 ---"))
+	return pending, nil
 }
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// testDistZip 生成一个包含 dist/ 目录的前端 zip
+func testDistZip(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, f := range []struct{ name, body string }{
+		{"dist/", ""},
+		{"dist/index.html", "<html></html>"},
+		{"dist/assets/app.js", "console.log(1)"},
+	} {
+		hdr := &zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: modified}
+		if f.body == "" {
+			hdr.SetMode(os.ModeDir | 0755)
+		} else {
+			hdr.SetMode(0644)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, f.body)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBuildFrontendArchiveDeterministic(t *testing.T) {
+	t.Chdir(t.TempDir())
+	zipData := testDistZip(t)
+
+	first, err := buildFrontendArchive(zipData, zstd.SpeedDefault, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// tar 头的时间精确到秒, 间隔超过一秒才能发现使用了解压时间的问题
+	time.Sleep(1100 * time.Millisecond)
+	second, err := buildFrontendArchive(zipData, zstd.SpeedDefault, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatal("相同的 dist.zip 生成了不同的归档")
+	}
+
+	dec, err := zstd.NewReader(bytes.NewReader(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dec.Close()
+	tr := tar.NewReader(dec)
+	names := make(map[string]*tar.Header)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names[hdr.Name] = hdr
+	}
+	hdr, ok := names["frontend/index.html"]
+	if !ok {
+		t.Fatalf("归档中缺少 frontend/index.html: %v", names)
+	}
+	if !hdr.ModTime.Equal(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("ModTime = %v, 应取自 zip 条目", hdr.ModTime)
+	}
+	if hdr.Uid != 0 || hdr.Gid != 0 || hdr.Uname != "" || hdr.Gname != "" {
+		t.Errorf("属主信息未清除: %d/%d %q/%q", hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname)
+	}
+	if hdr.Mode != 0644 {
+		t.Errorf("Mode = %o, 应为 zip 中的 0644", hdr.Mode)
+	}
+}
+
+// benchBundle 生成 size 字节、类似打包后 JS 的测试数据
+func benchBundle(size int) []byte {
+	var b bytes.Buffer
+	b.Grow(size + 64)
+	for i := 0; b.Len() < size; i++ {
+		fmt.Fprintf(&b, "function f%d(a,b){return a*%d+b.length%%%d;}\n", i, i%97, i%13+1)
+	}
+	return b.Bytes()[:size]
+}
+
+// BenchmarkCompressZstd 对比同一输入下 EncodeAll 一次性压缩与流式压缩的耗时和内存分配
+func BenchmarkCompressZstd(b *testing.B) {
+	data := benchBundle(streamThreshold)
+	b.Run("buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+			if err != nil {
+				b.Fatal(err)
+			}
+			encoder.EncodeAll(data, make([]byte, 0, len(data)))
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			if err := compressZstdStream(io.Discard, bytes.NewReader(data), zstd.SpeedDefault, 1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkCompressZstdThreads 显示流式压缩在不同 -compress-threads 下的速度
+func BenchmarkCompressZstdThreads(b *testing.B) {
+	data := benchBundle(2 * streamThreshold)
+	threads := []int{1, 2, 4, runtime.NumCPU()}
+	slices.Sort(threads)
+	for _, threads := range slices.Compact(threads) {
+		b.Run(fmt.Sprintf("threads=%d", threads), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for b.Loop() {
+				if _, err := compressZstd(data, zstd.SpeedDefault, threads); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestCompressZstdThreadsSameOutput(t *testing.T) {
+	data := benchBundle(streamThreshold)
+	want, err := compressZstd(data, zstd.SpeedDefault, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, threads := range []int{2, 4} {
+		got, err := compressZstd(data, zstd.SpeedDefault, threads)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("threads=%d 的压缩结果与单线程不同", threads)
+		}
+	}
+}
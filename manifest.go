@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// loadManifest 获取版本清单, 清单为组件名到批准版本的映射, 例如:
+//
+//	{"sub-store": "2.19.0", "sub-store-frontend": "2.15.1"}
+//
+// 清单中未列出的组件仍按最新 release 更新
+func loadManifest(cfg *Config) (map[string]string, error) {
+	var manifest map[string]string
+	err := cfg.Retry.do(tr("获取版本清单"), func() error {
+		req, err := newRequest(http.MethodGet, cfg.ManifestURL, nil)
+		if err != nil {
+			return permanent(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return statusError(tr("版本清单请求失败"), resp)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+			return permanent(fmt.Errorf(tr("解析版本清单失败: %w"), err))
+		}
+		return nil
+	})
+	return manifest, err
+}
+
+// committedTag 从目标目录的元数据文件中读取组件已提交的版本, 找不到时返回空字符串
+// 使用 -path-template 时各版本的文件可能位于子目录中, 因此查找整个目录树, 取最近生成的一份
+func committedTag(destDir, component string) string {
+	var latest *Metadata
+	walkMetadata(destDir, func(_ string, meta *Metadata) {
+		if meta.Component == component && (latest == nil || meta.UpdatedAt.After(latest.UpdatedAt)) {
+			latest = meta
+		}
+	})
+	if latest == nil {
+		return ""
+	}
+	return latest.Tag
+}
+
+// versionPresent 判断目标目录中是否已有组件 tag 版本的文件: 存在记录该版本的元数据, 且其对应的文件仍在
+// 用于 -path-template 按版本区分路径时的更新检查
+func versionPresent(destDir, component, tag string) bool {
+	found := false
+	walkMetadata(destDir, func(path string, meta *Metadata) {
+		if meta.Component == component && sameTag(meta.Tag, tag) && fileExists(strings.TrimSuffix(path, metadataSuffix)) {
+			found = true
+		}
+	})
+	return found
+}
+
+// outputsIntact 判断元数据记录的组件 tag 版本的文件是否都还在且内容未被改动:
+// 文件存在, .zst 文件以 zstd 魔数开头, 且元数据中有当前 -hash-algo 的摘要时与之一致
+// 没有该版本的元数据时返回 false
+func outputsIntact(destDir, component, tag string) bool {
+	found, intact := false, true
+	walkMetadata(destDir, func(path string, meta *Metadata) {
+		if meta.Component != component || !sameTag(meta.Tag, tag) {
+			return
+		}
+		found = true
+		file := strings.TrimSuffix(path, metadataSuffix)
+		if !fileExists(file) || invalidZstd(file) {
+			intact = false
+			return
+		}
+		if want := meta.digest(); want != "" {
+			if sum, err := fileHash(file); err != nil || hex.EncodeToString(sum) != want {
+				intact = false
+			}
+		}
+	})
+	return found && intact
+}
+
+// walkMetadata 对 destDir 目录树中每个可解析的元数据文件调用 fn, 读取失败的文件直接跳过
+func walkMetadata(destDir string, fn func(path string, meta *Metadata)) {
+	filepath.WalkDir(destDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, metadataSuffix) {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		var meta Metadata
+		if json.Unmarshal(data, &meta) == nil {
+			fn(p, &meta)
+		}
+		return nil
+	})
+}
+
+// normalizeTag 去掉版本号前的 v / V 前缀, 仅用于比较, 显示和提交时仍使用原始 tag
+func normalizeTag(tag string) string {
+	return strings.TrimLeft(strings.TrimSpace(tag), "vV")
+}
+
+// sameTag 判断两个 tag 是否指同一版本, 上游有时会切换是否带 v 前缀, 如 v2.0.0 与 2.0.0
+func sameTag(a, b string) bool {
+	return a != "" && b != "" && normalizeTag(a) == normalizeTag(b)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputsIntact(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(js, zst string)
+		want   bool
+	}{
+		{"完好", func(js, zst string) {}, true},
+		{"文件被删除", func(js, zst string) { os.Remove(zst) }, false},
+		{"被替换为 HTML 错误页", func(js, zst string) { os.WriteFile(zst, []byte("<html>502</html>"), 0644) }, false},
+		{"内容与元数据不一致", func(js, zst string) { os.WriteFile(js, []byte("changed"), 0644) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			js := filepath.Join(dest, "sub-store.bundle.js")
+			zst := js + ".zst"
+			files := map[string][]byte{
+				js:  []byte("console.log(1)"),
+				zst: append(append([]byte{}, zstdMagic...), 0, 0, 0),
+			}
+			for path, data := range files {
+				if err := os.WriteFile(path, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+				meta := newMetadata("sub-store", "v2.19.0", &ReleaseAsset{Name: "sub-store.bundle.js"}, downloadSource{}, path, data)
+				if _, err := writeMetadata(path, meta); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tt.damage(js, zst)
+			if got := outputsIntact(dest, "sub-store", "v2.19.0"); got != tt.want {
+				t.Errorf("outputsIntact = %v, want %v", got, tt.want)
+			}
+			if outputsIntact(dest, "sub-store", "v2.20.0") {
+				t.Error("没有该版本的元数据时应返回 false")
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+// messagesEN 为 -lang en 时使用的英文译文, 键为代码中的中文原文
+var messagesEN = map[string]string{
+	"%d 个已提交文件与上游 %s 不一致":          "%d committed files differ from upstream %s",
+	"%q 的协议不受支持":                   "%q uses an unsupported scheme",
+	"%q 缺少主机":                      "%q is missing a host",
+	"%s (%s) 最近 %d 个 release:\n%s": "latest %[3]d %[1]s releases (%[2]s):\n%[4]s",
+	"%s git 操作失败: %w":              "%s git operation failed: %w",
+	"%s git 操作校验失败: %w":            "%s git dry run failed: %w",
+	"%s 不是 git 仓库":                 "%s is not a git repository",
+	"%s 不是 git 仓库: %w":             "%s is not a git repository: %w",
+	"%s 不是 git 工作区":                "%s is not a git work tree",
+	"%s 不是目录":                      "%s is not a directory",
+	"%s 与 %s 解压后同名, 已忽略 %s":        "%s and %s decompress to the same name, ignoring %s",
+	"%s 中没有后端元数据, 无法确定已提交的版本":      "no backend metadata in %s, cannot determine the committed version",
+	"%s 为 %s 压缩格式, 解压后大小: %s":      "%s is %s compressed, decompressed size: %s",
+	"%s 压缩后大小: %s":                 "%s compressed size: %s",
+	"%s 可用空间不足: 剩余 %s, 至少需要 %s (含 -min-free-mb %d MiB 余量)": "not enough free space on %s: %s left, need at least %s (including the -min-free-mb %d MiB margin)",
+	"%s 失败: %v\n输出: %s":            "%s failed: %v\noutput: %s",
+	"%s 已是清单批准的版本, 无需更新。":          "%s is already at the approved version, nothing to update.",
+	"%s 校验通过":                      "%s checksum OK",
+	"%s失败 (第 %d/%d 次): %v, %s 后重试": "%s failed (attempt %d/%d): %v, retrying in %s",
+	"%s文件上传失败: %w":                 "%s upload failed: %w",
+	"%s文件上传成功: %s":                 "%s uploaded to %s",
+	"%s文件已是最新, 但元数据需要同步 (检查模式, 不做任何修改)":  "%s files are up to date but metadata needs syncing (dry run, no changes made)",
+	"%s文件已是最新，无需更新。":                     "%s files are up to date, nothing to update.",
+	"%s文件有可用更新: %s (检查模式, 不做任何修改)":       "%s update available: %s (dry run, no changes made)",
+	"%s文件有更新，准备替换...":                    "%s files changed, replacing...",
+	"%s版本 %s 发布于 %s, 早于 -since %s, 跳过更新": "%s release %s was published at %s, before -since %s, skipping",
+	"%w (提交已保留在本地): %w":                  "%w (the commit is kept locally): %w",
+	", 行数 %d -> %d (%+d)":                ", lines %d -> %d (%+d)",
+	"--- 所有检查已完成 ---":                    "--- all checks finished ---",
+	"-asset 不能为空":                        "-asset must not be empty",
+	"-download-workers 至少为 1: %d":        "-download-workers must be at least 1: %d",
+	"-input-compressions 中的 %q 不受支持":     "%q in -input-compressions is not supported",
+	"-max-download-mb 不能为负数: %d":         "-max-download-mb must not be negative: %d",
+	"-min-free-mb 不能为负数: %d":             "-min-free-mb must not be negative: %d",
+	"-push-attempts 至少为 1: %d":           "-push-attempts must be at least 1: %d",
+	"-retry-jitter 需在 0~1 之间: %v":        "-retry-jitter must be between 0 and 1: %v",
+	"-watch 和 -watch-jitter 不能为负数":       "-watch and -watch-jitter must not be negative",
+	"<- 使用":                     "<- selected",
+	"GitHub API 请求失败":           "GitHub API request failed",
+	"[一致] %s":                   "[match] %s",
+	"[不一致] %s":                  "[mismatch] %s",
+	"[失败] %s: %v":               "[failed] %s: %v",
+	"[成功] %s (%s)":              "[ok] %s (%s)",
+	"[找到] %s: %s":               "[found] %s: %s",
+	"[缺失] %s":                   "[missing] %s",
+	"git add --dry-run 输出:\n%s": "git add --dry-run output:\n%s",
+	"git 推送":                    "git push",
+	"git 提交":                    "git commit",
+	"git 添加":                    "git add",
+	"release 缓存已损坏, 将忽略: %v":    "release cache is corrupt, ignoring it: %v",
+	"sha256 不匹配: 期望 %x, 实际 %x":  "sha256 mismatch: expected %x, got %x",
+	"上传 %s 失败: %w":              "uploading %s failed: %w",
+	"下次检查将在 %s 后进行":             "next check in %s",
+	"下载%s文件":                    "download %s file",
+	"下载%s文件失败: %w":              "downloading %s file failed: %w",
+	"下载后端文件失败: %w":              "downloading backend files failed: %w",
+	"下载地址:":                     "download URL:",
+	"下载请求失败":                    "download request failed",
+	"不可用":                       "unavailable",
+	"代理 %s: %s":                 "proxy %s: %s",
+	"代理\t结果\t延迟\t":              "PROXY\tRESULT\tLATENCY\t",
+	"代理检测结果:\n":                 "proxy check results:\n",
+	"使用 %s 前缓存的 %s release 信息 (-no-cache 可跳过缓存)": "using %s release info cached %s ago (-no-cache skips the cache)",
+	"使用代理:":                 "using proxy:",
+	"保存状态文件失败: %v":          "saving state file failed: %v",
+	"写入 release 缓存失败: %v":   "writing release cache failed: %v",
+	"写入%s元数据失败: %w":         "writing %s metadata failed: %w",
+	"写入%s文件失败: %w":          "writing %s file failed: %w",
+	"创建 zip reader 失败: %w":  "creating zip reader failed: %w",
+	"创建 zstd writer 失败: %w": "creating zstd writer failed: %w",
+	"创建文件失败: %w":            "creating file failed: %w",
+	"创建目录失败: %w":            "creating directory failed: %w",
+	"创建目标目录失败: %v":          "creating destination directory failed: %v",
+	"删除 %s 失败: %v":          "removing %s failed: %v",
+	"前端":                    "frontend",
+	"前端最新版本:":               "latest frontend version:",
+	"压缩后端文件失败: %w":          "compressing backend file failed: %w",
+	"变化:":                   "change:",
+	"可用":                    "available",
+	"后端":                    "backend",
+	"后端最新版本:":               "latest backend version:",
+	"响应内容超过上限 %s, 已中止下载":    "response exceeds the %s limit, download aborted",
+	"失败": "failed",
+	"守护模式: 每 %s 检查一次更新 (随机抖动 %s)": "watch mode: checking for updates every %s (jitter %s)",
+	"实际下载地址:":         "actual download URL:",
+	"将推送到 origin/%s":  "would push to origin/%s",
+	"将提交 %d 个文件: %s":  "would commit %d files: %s",
+	"尝试镜像地址: %s":      "trying mirror: %s",
+	"已上传 %s (%s)":     "uploaded %s (%s)",
+	"已下载%s文件: %s":     "downloaded %s file: %s",
+	"已切换到 %s 分支":      "switched to branch %s",
+	"已删除:":            "removed:",
+	"已同步 %d 个%s元数据文件": "synced %d %s metadata files",
+	"已在本地完成提交, 但推送失败, 本地仓库领先于远程。可稍后手动执行 git push origin %s, 或下次运行时加上 -retry-push": "committed locally but the push failed, the local branch is ahead of the remote. Run git push origin %s manually later, or pass -retry-push on the next run",
+	"已完成 git 提交, 请手动推送到远程仓库":                "git commit done, push to the remote manually",
+	"已完成 git 提交和远程仓库推送":                     "git commit and push done",
+	"已将%s文件更新到: %s":                         "updated %s file: %s",
+	"已提交文件与上游 %s 一致":                        "committed files match upstream %s",
+	"已提交的后端版本:":                             "committed backend version:",
+	"已提交的前端版本:":                             "committed frontend version:",
+	"获取前端 release %s 失败: %v":                "fetching frontend release %s failed: %v",
+	"已跳过 git 提交 (-no-commit)":               "skipped git commit (-no-commit)",
+	"强制完整扫描所有候选代理...":                       "forcing a full scan of all candidate proxies...",
+	"成功更新 %s 到 %s":                          "updated %s to %s",
+	"打开 zip 内文件失败: %w":                      "opening file in zip failed: %w",
+	"收到退出信号, 守护模式结束":                        "received a signal, stopping watch mode",
+	"文件大小 %s 超过上限 %s":                       "file size %s exceeds the %s limit",
+	"无效的 -asset %q: %w":                     "invalid -asset %q: %w",
+	"无效的 -compress-pattern: %w":             "invalid -compress-pattern: %w",
+	"无效的 -format: %q":                       "invalid -format: %q",
+	"无效的 -lang: %q":                         "invalid -lang: %q",
+	"无效的 -level: %q":                        "invalid -level: %q",
+	"无效的 -manifest-url: %q":                 "invalid -manifest-url: %q",
+	"无效的 -match-field: %q":                  "invalid -match-field: %q",
+	"无效的 -proxy: %w":                        "invalid -proxy: %w",
+	"无效的 -upload-url: %q":                   "invalid -upload-url: %q",
+	"无效的候选代理: %w":                           "invalid proxy candidate: %w",
+	"无效的资源匹配模式 %q: %w":                      "invalid asset pattern %q: %w",
+	"无法执行 git: %w":                          "cannot run git: %w",
+	"无法获取 %s 的可用空间: %v":                     "cannot get free space of %s: %v",
+	"无法解析时间 %q, 应为 2006-01-02 或 RFC3339 格式": "cannot parse time %q, expected 2006-01-02 or RFC3339",
+	"无法计算当前%s文件哈希: %v":                      "cannot hash current %s file: %v",
+	"无法连接到 GitHub: 代理与直连均不可用, 请检查网络连接":      "cannot reach GitHub: neither a proxy nor a direct connection works, check your network",
+	"服务器返回 %s: %s":                          "server returned %s: %s",
+	"未找到 %s 匹配 %s 的资源":                      "no asset with %s matching %s",
+	"未找到 dist.zip":                          "dist.zip not found",
+	"未找到可用代理，检测直连...":                       "no usable proxy found, checking direct connection...",
+	"未推送的提交已推送到远程仓库":                        "pending commits pushed to the remote",
+	"未知的配置项: %q":                            "unknown config key: %q",
+	"校验":                                    "checksum",
+	"校验前端文件失败: %w":                          "verifying frontend file failed: %w",
+	"校验文件 %s 内容为空":                          "checksum file %s is empty",
+	"校验文件 %s 格式无效: %w":                      "checksum file %s is malformed: %w",
+	"校验文件 %s 格式无效: %q 不是 sha256 校验和":        "checksum file %s is malformed: %q is not a sha256 checksum",
+	"检测到 %d 个未推送的提交, 重新推送到 origin/%s...":    "found %d unpushed commits, pushing to origin/%s...",
+	"没有需要清理的文件":                             "nothing to clean",
+	"版本\t发布时间\t资源数\t":                       "TAG\tPUBLISHED\tASSETS\t",
+	"版本清单批准的 %s 版本: %s, 已提交版本: %s":          "manifest approves %s version %s, committed version: %s",
+	"版本清单请求失败":                              "manifest request failed",
+	"目标仓库不在 %s 分支 (当前为 %s), 可加上 -checkout-branch 自动切换": "destination repo is not on branch %s (currently %s), pass -checkout-branch to switch automatically",
+	"目标仓库当前分支为 %s, 期望为 %s":                             "destination repo is on branch %s, expected %s",
+	"目标目录不可写: %w":                                      "destination directory is not writable: %w",
+	"直连可用，将不设置代理":                                      "direct connection works, not using a proxy",
+	"获取 %s release ":                                   "fetch %s release",
+	"获取 %s release %s ":                                "fetch %s release %s",
+	"获取 %s release 列表":                                 "fetch %s release list",
+	"获取%s release 列表失败: %v":                            "fetching %s release list failed: %v",
+	"获取前端 release 失败: %w":                              "fetching frontend release failed: %w",
+	"获取后端 release %s 失败: %v":                           "fetching backend release %s failed: %v",
+	"获取后端 release 失败: %w":                              "fetching backend release failed: %w",
+	"获取版本清单":                                           "fetch version manifest",
+	"获取版本清单失败: %w":                                     "fetching version manifest failed: %w",
+	"解压 %s 失败: %w":                                     "decompressing %s failed: %w",
+	"解压后的内容超过上限 %s":                                    "decompressed content exceeds the limit of %s",
+	"解压文件失败: %w":                                       "extracting file failed: %w",
+	"解析版本清单失败: %w":                                     "parsing version manifest failed: %w",
+	"解析配置文件 %s 失败: %w":                                 "parsing config file %s failed: %w",
+	"警告: 下载大小 %d 字节与 API 声明的 %d 字节不一致":                 "warning: downloaded %d bytes but the API reports %d bytes",
+	"读取状态文件失败: %v":                                     "reading state file failed: %v",
+	"读取配置文件 %s 失败: %w":                                 "reading config file %s failed: %w",
+	"资源大小: %s, 创建于: %s, 更新于: %s":                       "asset size: %s, created: %s, updated: %s",
+	"距离上次%s提交不足 %s, 将在 %s 后再更新":                        "last %s commit was less than %s ago, will update in %s",
+	"跳转到 %s 后请求失败: %w":                                 "request failed after redirect to %s: %w",
+	"跳过目标目录中的文件: %s":                                   "skipping file in destination directory: %s",
+	"通过":                                               "pass",
+	"配置有误:\n%v\n":                                      "invalid configuration:\n%v\n",
+	"配置项 %q 取值无效: %w":                                  "invalid value for config key %q: %w",
+	"配置项 %q 的取值类型不受支持":                                 "unsupported value type for config key %q",
+	"重新推送未推送的提交失败: %v":                                 "pushing pending commits failed: %v",
+	"%s 的资源尚未全部上传, %s 后重新检查 (最长等待至 %s)":                "assets of %s are not all uploaded yet, checking again in %s (waiting until %s at most)",
+	"%s 的资源已上传":                                        "assets of %s are uploaded",
+	"等待 %s 上传资源超时 (-wait-asset %s): %w":                "timed out waiting for %s assets (-wait-asset %s): %w",
+	"非法文件路径: %s":                                       "illegal file path: %s",
+	"未找到资源":                                            "asset not found",
+	"下载失败":                                             "download failed",
+	"git 操作失败":                                         "git operation failed",
+	"代理与直连均不可用":                                        "neither proxy nor direct connection available",
+	"git 推送失败":                                         "git push failed",
+	"读取代理配置 %s 失败: %w":                                 "reading proxy config %s failed: %w",
+	"解析代理配置 %s 失败: %w":                                 "parsing proxy config %s failed: %w",
+	"配置中没有 system-proxy、mixed-port 或 port":             "config has no system-proxy, mixed-port or port",
+	"读取代理配置失败, 将继续扫描候选代理: %v":                          "reading proxy config failed, scanning proxy candidates instead: %v",
+	"从 %s 读取到代理: %s":                                   "read proxy from %s: %s",
+	"下载缓存 %s 内容与摘要不符, 已删除":                             "download cache entry %s does not match its digest, removed",
+	"写入下载缓存失败: %v":                                     "writing download cache failed: %v",
+	"已从下载缓存中清除 %s (%s)":                                "evicted %s from the download cache (%s)",
+	"使用下载缓存中的%s文件: %s":                                 "using cached %s file: %s",
+	"-download-cache-max-mb 不能为负数: %d":                 "-download-cache-max-mb must not be negative: %d",
+	"文件已写入但未提交, 可稍后运行 update-sub-store commit-only 重新提交": "files were written but not committed, run update-sub-store commit-only later to retry the commit",
+	"没有待提交的文件": "no pending commits",
+	"%s %s 的文件已无改动, 可能已手动提交, 清除待提交记录": "files of %s %s have no changes, probably committed manually, clearing the pending record",
+	"重新提交 %s %s: %s": "committing %s %s again: %s",
+	"现有文件 %s 不是有效的 zstd 文件, 将强制替换":                                             "existing file %s is not valid zstd, forcing a replacement",
+	"本次检查失败, 已连续失败 %d 次":                                                       "check failed, %d consecutive failures",
+	"连续失败达到 -watch-max-failures %d 次, 守护模式退出":                                  "reached -watch-max-failures %d consecutive failures, stopping watch mode",
+	"-watch-max-failures 不能为负数: %d":                                            "-watch-max-failures must not be negative: %d",
+	"-metrics-addr 只在守护模式 (-watch) 下生效, 已忽略":                                   "-metrics-addr only applies in watch mode (-watch), ignored",
+	"指标服务已启动: http://%s/metrics":                                               "metrics server listening on http://%s/metrics",
+	"指标服务出错: %v":                                                               "metrics server error: %v",
+	"%s文件未变化 (304), 使用下载缓存: %s":                                                "%s file not modified (304), using the download cache: %s",
+	"下载缓存中的%s文件与期望的校验和不符, 已删除缓存并重新下载":                                          "the cached %s file does not match the expected checksum; removed it and downloading again",
+	"%s 未发送条件头却返回 304":                                                         "%s returned 304 to a request without conditional headers",
+	"无效的检测地址 %q":                                                               "invalid test URL %q",
+	"无效的状态码 %d, 应在 100~599 之间":                                                 "invalid status code %d, must be between 100 and 599",
+	"读取 -input 文件失败: %w":                                                       "reading -input file failed: %w",
+	"使用本地文件 %s (%s), 版本: %s":                                                   "using local file %s (%s), version: %s",
+	"无效的 -input: %w":                                                           "invalid -input: %w",
+	"无效的 -input: %s 是目录":                                                       "invalid -input: %s is a directory",
+	"-o 目前只支持 - (标准输出): %q":                                                    "-o only supports - (stdout) for now: %q",
+	"-o - 只能输出单个文件, 当前%s产物有 %d 个文件, 可调整 -asset 或 -format":                      "-o - can only write a single file, the %s artifact has %d files, adjust -asset or -format",
+	"写入标准输出失败: %w":                                                             "writing to stdout failed: %w",
+	"已将 %s 写到标准输出 (%s)":                                                        "wrote %s to stdout (%s)",
+	"无效的 -hash-algo: %q, 可选 %s":                                                "invalid -hash-algo: %q, choose from %s",
+	"校验 GitHub token ":                                                         "validate GitHub token",
+	"GitHub token 授权范围: %q":                                                    "GitHub token scopes: %q",
+	"GitHub token 无效或已过期 (%s), 请检查 -token 或 GITHUB_TOKEN":                      "GitHub token is invalid or expired (%s), check -token or GITHUB_TOKEN",
+	"GitHub token 没有读取 %s 的权限 (%s)":                                            "GitHub token has no permission to read %s (%s)",
+	"GitHub token 没有推送到 %s 的权限, 请为 token 授予该仓库的 contents 写权限":                  "GitHub token has no permission to push to %s; grant it contents write access to that repository",
+	"解析仓库 %s 的信息失败: %w":                                                        "parsing repository information for %s failed: %w",
+	"%w, 需要的权限: %s":                                                            "%w, required permissions: %s",
+	"GitHub token 校验通过":                                                        "GitHub token validated",
+	"直连: 可用 (%s)":                                                              "direct: available (%s)",
+	"直连: 不可用":                                                                  "direct: unavailable",
+	"建议使用代理:":                                                                  "suggested proxy:",
+	"没有可用的代理, 可直接连接 GitHub":                                                    "no usable proxy, GitHub is reachable directly",
+	"无效的 -file-mode: %q, 应为 0644 形式的八进制权限":                                     "invalid -file-mode: %q, expected octal permissions like 0644",
+	"后端资源摘要与上次相同, 跳过下载和压缩。":                                                    "Backend asset digests unchanged since last run, skipping download and compression.",
+	"-dest %s 不在 -repo-path %s 之内":                                             "-dest %s is not inside -repo-path %s",
+	"-commit-type %q 与 -commit-scope %q 生成的提交信息 %q 不符合 conventional commit 格式": "-commit-type %q and -commit-scope %q produce commit message %q, which is not a conventional commit",
+	"-compress-threads 至少为 1: %d":                                              "-compress-threads must be at least 1: %d",
+	"收到中断信号, 已清理临时文件":                                                          "interrupted, temporary files removed",
+	"没有可用更新, 按 -fail-on-no-update 以非零退出码退出":                                    "no update available, exiting with a non-zero code because of -fail-on-no-update",
+	"读取 CA 证书失败: %w":                                                           "reading CA certificate failed: %w",
+	"%s 中没有有效的 PEM 证书":                                                         "%s contains no valid PEM certificate",
+	"警告: 已指定 -insecure-skip-verify, 不校验 HTTPS 证书, 下载内容可能被篡改! 仅应在排查问题时临时使用": "WARNING: -insecure-skip-verify is set, HTTPS certificates are NOT verified and downloads may be tampered with! Use only temporarily for troubleshooting",
+	"无法为默认 Transport 设置 TLS 配置":                           "cannot set TLS config on the default transport",
+	"校验和不匹配":                                              "checksum mismatch",
+	"%s文件的校验和始终不匹配, 已尝试 %d 个下载地址: %w":                     "%s file checksum never matched after trying %d download URLs: %w",
+	"%s %s 及其资源早于上次成功运行 (%s), 跳过下载 (-since-last-run)":     "%s %s and its assets predate the last successful run (%s), skipping download (-since-last-run)",
+	"origin (%s) 不是 GitHub 仓库, 无法通过 API 确认推送结果":           "origin (%s) is not a GitHub repository, cannot confirm the push via the API",
+	"确认远程分支 ":                                             "confirm remote branch",
+	"远程分支 %s/%s 的最新提交为 %s, 与本地提交 %s 不一致":                  "remote branch %s/%s is at %s, which does not match local commit %s",
+	"已确认远程分支 %s/%s 包含提交 %s":                               "confirmed remote branch %s/%s contains commit %s",
+	"%w: 推送后远程分支未包含本次提交: %w":                              "%w: remote branch does not contain the commit after push: %w",
+	"-verify-push 需要同时指定 -push 和 -token (或 GITHUB_TOKEN)": "-verify-push requires -push and -token (or GITHUB_TOKEN)",
+	"候选代理 %s 不符合 -proxy-allow / -proxy-deny, 已跳过":         "proxy candidate %s does not satisfy -proxy-allow / -proxy-deny, skipped",
+	"配置的代理 %s 不符合 -proxy-allow / -proxy-deny, 不会使用":       "configured proxy %s does not satisfy -proxy-allow / -proxy-deny, not using it",
+	"无效的代理匹配规则: %q":                                       "invalid proxy pattern: %q",
+	"解压响应失败: %w":                                          "decompressing response failed: %w",
+	"获取 %s 最新 release 失败: %v":                             "fetching the latest release of %s failed: %v",
+	"当前版本: %s, 最新版本: %s":                                  "current version: %s, latest version: %s",
+	"已是最新版本, 无需更新。":                                       "Already the latest version, nothing to update.",
+	"有可用的新版本 %s (检查模式, 不做任何修改)":                           "new version %s available (check mode, no changes made)",
+	"release %s 中没有适用于 %s/%s 的可执行文件":                      "release %s has no executable for %s/%s",
+	"获取新版本校验和失败: %v":                                      "fetching the checksum of the new version failed: %v",
+	"release %s 中没有 %s 的校验和, 为安全起见不自动替换":                  "release %s has no checksum for %s, refusing to replace the executable",
+	"新版本":                "new version",
+	"无法确定当前程序路径: %v":     "cannot determine the executable path: %v",
+	"替换 %s 失败: %v":       "replacing %s failed: %v",
+	"已将 %s 更新到 %s":       "updated %s to %s",
+	"修订上一次由本工具生成的提交 %s":  "amending the previous commit %s generated by this tool",
+	"将修订上一次由本工具生成的提交 %s": "would amend the previous commit %s generated by this tool",
+	"-amend 依靠 Generated-by trailer 识别本工具生成的提交, 不能与 -no-trailers 同时使用": "-amend relies on the Generated-by trailer to recognise commits made by this tool and cannot be combined with -no-trailers",
+	"未找到 %s 匹配正则 %s 的资源":                                "no asset whose %s matches regexp %s",
+	"正则 %s 匹配到多个资源, 已选择 %s, 忽略 %s":                      "regexp %s matched several assets, selected %s and ignored %s",
+	"无效的 -asset-regex %q: %w":                           "invalid -asset-regex %q: %w",
+	"提交信息预览 (检查模式, 不会提交):\n%s":                          "commit message preview (check mode, nothing is committed):\n%s",
+	"读取 -proxy-file %s 失败: %w":                          "reading -proxy-file %s failed: %w",
+	"%s 第 %d 行: %w":                                     "%s line %d: %w",
+	"代理检测超过 %s, 停止等待":                                   "proxy detection exceeded %s, giving up",
+	"代理检测超过 %s, 使用已完成的检测结果":                             "proxy detection exceeded %s, using the results finished so far",
+	"-proxy-detect-timeout 不能为负数: %s":                   "-proxy-detect-timeout must not be negative: %s",
+	"读取 GitHub App 私钥失败: %w":                            "reading the GitHub App private key failed: %w",
+	"解析 GitHub App 私钥 %s 失败: %w":                        "parsing the GitHub App private key %s failed: %w",
+	"无法确定 GitHub App 的安装, 请指定 -app-installation-id: %w": "cannot determine the GitHub App installation, specify -app-installation-id: %w",
+	"不是 PEM 格式":                                         "not in PEM format",
+	"不是 RSA 私钥":                                         "not an RSA private key",
+	"GitHub App 认证失败":                                   "GitHub App authentication failed",
+	"查找 GitHub App 在 %s 的安装失败: %w":                      "looking up the GitHub App installation on %s failed: %w",
+	"签发 GitHub App 安装 token 失败: %w":                     "creating a GitHub App installation token failed: %w",
+	"已签发 GitHub App 安装 token, 有效期至 %s":                  "created a GitHub App installation token, valid until %s",
+	"-app-id 与 -app-key 需要同时指定":                         "-app-id and -app-key must be specified together",
+	"无法为资源下载单独设置代理":                                     "cannot configure a separate proxy for asset downloads",
+	"代理只用于 %s 请求, 其余请求直连":                               "the proxy is only used for %s requests, other requests connect directly",
+	"警告: 直连不可用, 不使用代理的请求可能失败":                           "warning: direct connection unavailable, requests that bypass the proxy may fail",
+	"无效的 -proxy-for: %q":                                "invalid -proxy-for: %q",
+	"生成运行摘要失败: %v":                                      "generating the run report failed: %v",
+	"写入运行摘要 %s 失败: %v":                                  "writing the run report %s failed: %v",
+	"警告: 本地时钟与 GitHub 服务器相差 %s, 将按服务器时间计算速率限制的重置时间":         "warning: the local clock differs from the GitHub server by %s, computing the rate limit reset from server time",
+	"警告: 速率限制的重置时间 %s 后超出合理范围, 按 %s 处理":                     "warning: rate limit reset in %s is out of range, using %s",
+	"%s: %s (已达到速率限制, %s 后重置)":                              "%s: %s (rate limit exceeded, resets in %s)",
+	"release %s 中没有 source map %s, 只更新后端文件":                 "release %s has no source map %s, updating the backend files only",
+	"%s %s 大小为 %s, 超过 -max-asset-size %d MiB, 请检查资源选择是否正确":  "%s %s is %s, exceeding -max-asset-size %d MiB; check the asset selection",
+	"-max-asset-size 不能为负数: %d":                             "-max-asset-size must not be negative: %d",
+	"[新增]":                                                  "[added]",
+	"[修改]":                                                  "[modified]",
+	"[未变化]":                                                 "[unchanged]",
+	"%s %s 的文件与已提交的内容相同, 跳过提交":                              "files of %s %s match the committed content, skipping the commit",
+	"-proxy-probe-attempts 至少为 1: %d":                       "-proxy-probe-attempts must be at least 1: %d",
+	"打开日志文件失败: %w":                                          "opening the log file failed: %w",
+	"日志文件轮转失败: %v":                                          "rotating the log file failed: %v",
+	"-log-max-mb 与 -log-keep 不能为负数: %d, %d":                 "-log-max-mb and -log-keep must not be negative: %d, %d",
+	"读取 -gpg-key 失败: %w":                                    "reading -gpg-key failed: %w",
+	"解析 -gpg-key %s 失败: %w":                                 "parsing -gpg-key %s failed: %w",
+	"已指定 -gpg-key, 但 release %s 中没有 %s 的签名文件 (.asc / .sig)": "-gpg-key is set but release %s has no signature file (.asc / .sig) for %s",
+	"签名":                  "signature",
+	"%s 的 GPG 签名校验失败: %w": "GPG signature verification of %s failed: %w",
+	"%s 的 GPG 签名校验通过":     "GPG signature of %s verified",
+	"%s 的大小从 %s 变为 %s (%+.1f%%), 超过 -max-size-change %g%%, 可能是资源有误; 确认无误后可加上 -force 更新": "%s changed size from %s to %s (%+.1f%%), exceeding -max-size-change %g%%; the asset may be wrong, add -force to update anyway",
+	"%s 的大小变化 %+.1f%%":                        "%s size changed by %+.1f%%",
+	"-max-size-change 不能为负数: %g":              "-max-size-change must not be negative: %g",
+	"警告: %v (已指定 -force, 继续更新)":               "warning: %v (-force given, updating anyway)",
+	"%s 不在 %s 之内, 无法确定在裸仓库中的提交路径":             "%s is not inside %s, cannot determine its path in the bare repository",
+	"创建临时工作树失败: %w":                           "failed to create temporary worktree: %w",
+	"警告: 删除临时工作树 %s 失败: %v":                   "warning: failed to remove temporary worktree %s: %v",
+	"复制 %s 到工作树失败: %w":                        "failed to copy %s into the worktree: %w",
+	"已在 %s 创建 %s 分支的工作树":                      "created worktree at %s for branch %s",
+	"%s 为裸仓库, 将在工作树中提交: %s":                   "%s is a bare repository, will commit in a worktree: %s",
+	"%s 为裸仓库, 将在 %s 分支的工作树中提交":                "%s is a bare repository, will commit in a worktree of branch %s",
+	"-proxy-samples 至少为 1: %d":                "-proxy-samples must be at least 1: %d",
+	"跳转 %d: %s -> %s":                         "redirect %d: %s -> %s",
+	"检测到循环跳转: 再次跳转到 %s":                       "redirect loop detected: redirected to %s again",
+	"跳转次数超过 -max-redirects %d":                "more than -max-redirects %d redirects",
+	"-max-redirects 不能为负数: %d":                "-max-redirects must not be negative: %d",
+	"警告: %s 返回的不是有效的 JSON, 未写入 -dump-release": "warning: %s did not return valid JSON, not written to -dump-release",
+	"警告: 写入 -dump-release %s 失败: %v":          "warning: failed to write -dump-release %s: %v",
+	"提交后校验未通过, 已回滚提交":                         "post-commit verification failed, commit rolled back",
+	"执行提交后校验: %s":                             "running post-commit verification: %s",
+	"提交后校验通过":                                 "post-commit verification passed",
+	"%w: 仓库没有之前的提交, 需手动处理: %w":                "%w: the repository has no earlier commit to roll back to, fix it manually: %w",
+	"提交后校验未通过, 且回滚失败, 需手动处理: %w":              "post-commit verification failed and the rollback failed, fix it manually: %w",
+	"已回滚 %s %s 的提交, 仓库恢复到 %s":                 "rolled back the %s %s commit, repository restored to %s",
+	"%s未通过提交后校验, 跳过上传":                        "%s failed post-commit verification, skipping upload",
+	"%s 的 API 摘要格式无效: %q":                     "invalid API digest for %s: %q",
+	"使用 API 声明的 sha256 校验 %s (不可变 release)":   "verifying %s against the API-provided sha256 (immutable release)",
+	"使用 API 声明的 sha256 校验 %s":                 "verifying %s against the API-provided sha256",
+	"无效的 -path-template %q: %w":               "invalid -path-template %q: %w",
+	"路径模板渲染为 %q, 不是 -dest 之内的相对路径":            "path template rendered to %q, which is not a relative path inside -dest",
+	"%s %s 的文件已存在, 无需更新。":                     "files for %s %s already exist, no update needed.",
+	"读取归档 %s 失败: %w":                          "failed to read archive %s: %w",
+	"归档 %s 中没有 %s 可执行文件":                      "archive %s contains no %s executable",
+	"%s 中没有 OpenPGP 公钥":                       "%s contains no OpenPGP public key",
+	"签名不是由 -gpg-key 中的密钥生成的":                  "the signature was not made by a key in -gpg-key",
+	"签名密钥已被吊销":                                "the signing key has been revoked",
+	"签名密钥已过期":                                 "the signing key has expired",
+	"签名已过期":                                   "the signature has expired",
+	"签名文件类型为 %s, 不是 OpenPGP 签名":               "signature file type is %s, not an OpenPGP signature",
+}
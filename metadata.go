@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Metadata 记录目标文件对应的上游 release 信息，与目标文件一同提交
+type Metadata struct {
+	Component      string    `json:"component"`
+	Tag            string    `json:"tag"`
+	Asset          string    `json:"asset"`
+	AssetSize      int64     `json:"asset_size"`
+	AssetCreatedAt time.Time `json:"asset_created_at"`
+	AssetUpdatedAt time.Time `json:"asset_updated_at"`
+	DownloadURL    string    `json:"download_url,omitempty"` // API 中的 browser_download_url
+	FinalURL       string    `json:"final_url,omitempty"`    // 跟随跳转后实际下载的地址, 不含查询参数
+	Mirror         string    `json:"mirror,omitempty"`       // 下载使用的镜像
+	Cached         bool      `json:"cached,omitempty"`       // 内容来自下载缓存, 未重新下载
+	File           string    `json:"file"`
+	Path           string    `json:"path,omitempty"` // 引用该文件的路径, 默认为仓库内的相对路径, 见 -metadata-prefix
+	FileSize       int64     `json:"file_size"`
+	SHA256         string    `json:"sha256,omitempty"`
+	SHA512         string    `json:"sha512,omitempty"`
+	BLAKE3         string    `json:"blake3,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Generator      string    `json:"generator"`
+}
+
+// metadataSuffix 是元数据文件相对目标文件的后缀
+const metadataSuffix = ".meta.json"
+
+// metadataPath 返回目标文件对应的元数据文件路径
+func metadataPath(destPath string) string {
+	return destPath + metadataSuffix
+}
+
+// writeMetadata 将元数据写入目标文件旁的 json 文件
+func writeMetadata(destPath string, meta *Metadata) (string, error) {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := metadataPath(destPath)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// formatSize 将字节数格式化为便于阅读的大小
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// newMetadata 根据 release 资源和写入的数据生成元数据
+func newMetadata(component, tag string, asset *ReleaseAsset, source downloadSource, destPath string, data []byte) *Metadata {
+	meta := &Metadata{
+		Component:      component,
+		Tag:            tag,
+		Asset:          asset.Name,
+		AssetSize:      asset.Size,
+		AssetCreatedAt: asset.CreatedAt,
+		AssetUpdatedAt: asset.UpdatedAt,
+		DownloadURL:    asset.BrowserDownloadURL,
+		FinalURL:       source.finalURL,
+		Mirror:         source.mirror,
+		Cached:         source.cached,
+		File:           filepath.Base(destPath),
+		FileSize:       int64(len(data)),
+		UpdatedAt:      time.Now().UTC(),
+		Generator:      "update-sub-store " + version,
+	}
+	// 摘要字段以算法命名, 只填写 -hash-algo 选择的一项
+	switch sum := hashHex(data); hashAlgo {
+	case "sha512":
+		meta.SHA512 = sum
+	case "blake3":
+		meta.BLAKE3 = sum
+	default:
+		meta.SHA256 = sum
+	}
+	return meta
+}
+
+// digest 返回元数据中当前 -hash-algo 对应的摘要, 未记录时返回空字符串
+func (m *Metadata) digest() string {
+	switch hashAlgo {
+	case "sha512":
+		return m.SHA512
+	case "blake3":
+		return m.BLAKE3
+	}
+	return m.SHA256
+}
+
+// readMetadata 读取目标文件 path 对应的元数据文件
+func readMetadata(path string) (*Metadata, error) {
+	data, err := os.ReadFile(metadataPath(path))
+	if err != nil {
+		return nil, err
+	}
+	var m Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// metadataStale 判断 path 对应的元数据文件是否缺失, 或与 expected 记录的内容不一致
+// 仅比较版本与文件信息, 生成时间、工具版本和下载来源的差异不视为过期
+func metadataStale(path string, expected *Metadata) bool {
+	m, err := readMetadata(path)
+	if err != nil {
+		return true
+	}
+	current := *m
+	current.UpdatedAt, current.Generator = expected.UpdatedAt, expected.Generator
+	current.FinalURL, current.Mirror, current.Cached = expected.FinalURL, expected.Mirror, expected.Cached
+	current.AssetCreatedAt = current.AssetCreatedAt.UTC()
+	current.AssetUpdatedAt = current.AssetUpdatedAt.UTC()
+	want := *expected
+	want.AssetCreatedAt = want.AssetCreatedAt.UTC()
+	want.AssetUpdatedAt = want.AssetUpdatedAt.UTC()
+	return current != want
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// metricComponents 为需要输出已提交版本的组件
+var metricComponents = []string{"sub-store", "sub-store-frontend"}
+
+// serveMetrics 在 addr 上以 Prometheus 文本格式提供守护模式的运行指标
+func serveMetrics(addr string, stats *watchStats, destDir string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		stats.writeMetrics(w, destDir)
+	})
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf(tr("指标服务出错: %v"), err)
+		}
+	}()
+	log.Printf(tr("指标服务已启动: http://%s/metrics"), addr)
+	return srv
+}
+
+// writeMetrics 按 Prometheus 文本格式输出当前统计
+func (s *watchStats) writeMetrics(w io.Writer, destDir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metric := func(name, typ, help string, lines ...string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		for _, l := range lines {
+			fmt.Fprintln(w, l)
+		}
+	}
+	var lastSuccess float64
+	if !s.lastSuccess.IsZero() {
+		lastSuccess = float64(s.lastSuccess.UnixMilli()) / 1000
+	}
+	metric("update_sub_store_last_success_timestamp_seconds", "gauge", "Unix time of the last successful check.",
+		fmt.Sprintf("update_sub_store_last_success_timestamp_seconds %.3f", lastSuccess))
+	metric("update_sub_store_updates_total", "counter", "Checks that applied an update.",
+		fmt.Sprintf("update_sub_store_updates_total %d", s.updates))
+	metric("update_sub_store_failures_total", "counter", "Checks that failed.",
+		fmt.Sprintf("update_sub_store_failures_total %d", s.failures))
+	metric("update_sub_store_consecutive_failures", "gauge", "Checks that failed in a row.",
+		fmt.Sprintf("update_sub_store_consecutive_failures %d", s.consecutiveFailures))
+
+	var tags []string
+	for _, c := range metricComponents {
+		if tag := committedTag(destDir, c); tag != "" {
+			tags = append(tags, fmt.Sprintf("update_sub_store_committed_tag_info{component=%s,tag=%s} 1", labelValue(c), labelValue(tag)))
+		}
+	}
+	metric("update_sub_store_committed_tag_info", "gauge", "Currently committed release tag per component.", tags...)
+
+	// /metrics 不需要认证, 代理地址中的密码不能出现在标签中
+	proxy := redactURL(s.proxy)
+	if proxy == "" {
+		proxy = "direct"
+	}
+	metric("update_sub_store_proxy_info", "gauge", "Proxy in use, \"direct\" when none.",
+		fmt.Sprintf("update_sub_store_proxy_info{proxy=%s} 1", labelValue(proxy)))
+}
+
+// labelValue 按 Prometheus 文本格式转义并加上引号
+func labelValue(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(v) + `"`
+}
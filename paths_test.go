@@ -0,0 +1,83 @@
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestDefaultDestDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("home", home)
+	want := filepath.Join(home, "subs-check", "assets")
+	if got := defaultDestDir(); got != want {
+		t.Errorf("defaultDestDir() = %q, want %q", got, want)
+	}
+}
+
+func TestIsWithin(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "repo")
+	type testCase struct {
+		path string
+		want bool
+	}
+	tests := []testCase{
+		{root, true},
+		{filepath.Join(root, "assets"), true},
+		{filepath.Join(root, "assets", "sub-store.bundle.js"), true},
+		{filepath.Join(root, "..foo"), true},
+		{filepath.Join(root, "assets", "..", ".."), false},
+		{filepath.Dir(root), false},
+		{root + "2", false},
+	}
+	if runtime.GOOS == "windows" {
+		tests = append(tests,
+			testCase{filepath.Join(root, `assets\frontend.tar.zst`), true},
+			testCase{`Z:\elsewhere\assets`, false},
+		)
+	}
+	for _, tt := range tests {
+		if got := isWithin(root, tt.path); got != tt.want {
+			t.Errorf("isWithin(%q, %q) = %v, want %v", root, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRelPaths(t *testing.T) {
+	root := t.TempDir()
+	got := relPaths(root, filepath.Join(root, "assets", "sub-store.bundle.js"), filepath.Join(root, "update-sub-store.state.json"))
+	want := []string{filepath.Join("assets", "sub-store.bundle.js"), "update-sub-store.state.json"}
+	if !slices.Equal(got, want) {
+		t.Errorf("relPaths = %q, want %q", got, want)
+	}
+}
+
+func TestMetadataRef(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "repo")
+	file := filepath.Join(repo, "assets", "sub-store.bundle.js")
+	type testCase struct {
+		prefix, strip string
+		file          string
+		want          string
+	}
+	tests := []testCase{
+		// 元数据中的路径在所有系统上都使用 /
+		{"", "", file, "assets/sub-store.bundle.js"},
+		{"", "assets/", file, "sub-store.bundle.js"},
+		{"public", "assets/", file, "public/sub-store.bundle.js"},
+		{"cdn/", "", file, "cdn/assets/sub-store.bundle.js"},
+	}
+	if runtime.GOOS == "windows" {
+		// 不在同一盘符时无法计算相对路径, 只记录文件名
+		tests = append(tests, testCase{"", "", `Z:\other\sub-store.bundle.js`, "sub-store.bundle.js"})
+	}
+	for _, tt := range tests {
+		cfg := &Config{RepoPath: repo, MetaPrefix: tt.prefix, MetaStripPrefix: tt.strip}
+		if got := cfg.metadataRef(tt.file); got != tt.want {
+			t.Errorf("metadataRef(%q) with prefix=%q strip=%q = %q, want %q", tt.file, tt.prefix, tt.strip, got, tt.want)
+		}
+	}
+}
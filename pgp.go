@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+)
+
+// loadPGPKeys 读取 ASCII armor 或二进制格式的公钥文件
+func loadPGPKeys(path string) (openpgp.EntityList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(tr("读取 -gpg-key 失败: %w"), err)
+	}
+	var keys openpgp.EntityList
+	if isArmored(data) {
+		keys, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	} else {
+		keys, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf(tr("解析 -gpg-key %s 失败: %w"), path, err)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf(tr("%s 中没有 OpenPGP 公钥"), path)
+	}
+	return keys, nil
+}
+
+// isArmored 判断 data 是否为 ASCII armor 格式
+func isArmored(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN PGP "))
+}
+
+// verifyPGPSignature 用 keys 校验 data 的分离签名 sig (ASCII armor 或二进制)
+// 密钥与签名的吊销、过期、子密钥绑定签名和签名用途标志均按 OpenPGP 规范检查
+func verifyPGPSignature(keys openpgp.EntityList, data, sig []byte) error {
+	if isArmored(sig) {
+		block, err := armor.Decode(bytes.NewReader(sig))
+		if err != nil {
+			return err
+		}
+		if block.Type != openpgp.SignatureType {
+			return fmt.Errorf(tr("签名文件类型为 %s, 不是 OpenPGP 签名"), block.Type)
+		}
+		_, err = openpgp.CheckDetachedSignature(keys, bytes.NewReader(data), block.Body, nil)
+		return pgpError(err)
+	}
+	_, err := openpgp.CheckDetachedSignature(keys, bytes.NewReader(data), bytes.NewReader(sig), nil)
+	return pgpError(err)
+}
+
+// pgpError 将常见的校验失败原因转换为更易读的错误
+func pgpError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, pgperrors.ErrUnknownIssuer):
+		return errors.New(tr("签名不是由 -gpg-key 中的密钥生成的"))
+	case errors.Is(err, pgperrors.ErrKeyExpired):
+		return errors.New(tr("签名密钥已过期"))
+	case errors.Is(err, pgperrors.ErrSignatureExpired):
+		return errors.New(tr("签名已过期"))
+	case errors.Is(err, pgperrors.ErrKeyRevoked):
+		return errors.New(tr("签名密钥已被吊销"))
+	}
+	return err
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// testPGPEntity 生成测试用的签名密钥, config 为 nil 时使用默认参数
+func testPGPEntity(t *testing.T, config *packet.Config) *openpgp.Entity {
+	t.Helper()
+	e, err := openpgp.NewEntity("test", "", "test@example.com", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// writePGPKey 将 e 的公钥写入临时文件, armored 决定是否使用 ASCII armor
+func writePGPKey(t *testing.T, e *openpgp.Entity, armored bool) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if armored {
+		w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Serialize(w); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+	} else if err := e.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.asc")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testPGPSign 生成 data 的分离签名
+func testPGPSign(t *testing.T, e *openpgp.Entity, data []byte, armored bool, config *packet.Config) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	if armored {
+		err = openpgp.ArmoredDetachSign(&buf, e, bytes.NewReader(data), config)
+	} else {
+		err = openpgp.DetachSign(&buf, e, bytes.NewReader(data), config)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestVerifyPGPSignature(t *testing.T) {
+	data := []byte("sub-store bundle")
+	signer := testPGPEntity(t, nil)
+
+	for _, armored := range []bool{true, false} {
+		keys, err := loadPGPKeys(writePGPKey(t, signer, armored))
+		if err != nil {
+			t.Fatalf("loadPGPKeys(armored=%v): %v", armored, err)
+		}
+		for _, sigArmored := range []bool{true, false} {
+			sig := testPGPSign(t, signer, data, sigArmored, nil)
+			if err := verifyPGPSignature(keys, data, sig); err != nil {
+				t.Errorf("有效签名 (key armored=%v, sig armored=%v) 校验失败: %v", armored, sigArmored, err)
+			}
+			if err := verifyPGPSignature(keys, []byte("tampered"), sig); err == nil {
+				t.Errorf("篡改后的数据 (sig armored=%v) 通过了校验", sigArmored)
+			}
+		}
+	}
+
+	keys, err := loadPGPKeys(writePGPKey(t, signer, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := testPGPSign(t, testPGPEntity(t, nil), data, true, nil)
+	if err := verifyPGPSignature(keys, data, sig); err == nil || !strings.Contains(err.Error(), "-gpg-key") {
+		t.Errorf("其他密钥的签名应报告未知签名者, got %v", err)
+	}
+
+	var pub bytes.Buffer
+	w, _ := armor.Encode(&pub, openpgp.PublicKeyType, nil)
+	signer.Serialize(w)
+	w.Close()
+	if err := verifyPGPSignature(keys, data, pub.Bytes()); err == nil {
+		t.Error("公钥文件被当作签名时应返回错误")
+	}
+}
+
+func TestVerifyPGPSignatureExpiredKey(t *testing.T) {
+	created := time.Now().Add(-48 * time.Hour)
+	config := &packet.Config{Time: func() time.Time { return created }, KeyLifetimeSecs: 3600}
+	signer := testPGPEntity(t, config)
+	keys, err := loadPGPKeys(writePGPKey(t, signer, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("sub-store bundle")
+	sig := testPGPSign(t, signer, data, true, &packet.Config{Time: func() time.Time { return created.Add(time.Minute) }})
+	if err := verifyPGPSignature(keys, data, sig); err == nil {
+		t.Error("过期密钥的签名通过了校验")
+	}
+}
+
+func TestVerifyPGPSignatureRevokedKey(t *testing.T) {
+	signer := testPGPEntity(t, nil)
+	data := []byte("sub-store bundle")
+	sig := testPGPSign(t, signer, data, true, nil)
+	if err := signer.RevokeKey(packet.KeyCompromised, "test", nil); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := loadPGPKeys(writePGPKey(t, signer, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyPGPSignature(keys, data, sig); err == nil {
+		t.Error("已吊销密钥的签名通过了校验")
+	}
+}
+
+func TestLoadPGPKeysEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.asc")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadPGPKeys(path); err == nil {
+		t.Error("空的公钥文件应返回错误")
+	}
+}
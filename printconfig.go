@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// secretFlags 为输出生效配置时需要隐藏取值的参数
+var secretFlags = map[string]bool{"token": true, "upload-token": true}
+
+// runPrintConfig 以 JSON 输出合并默认值、配置文件、环境变量和命令行参数后的生效配置
+// 键名与配置文件相同, 可直接作为 -config 使用; token 与 URL 中的密码会被隐藏
+func runPrintConfig(cfg *Config) int {
+	values := make(map[string]any)
+	funcValues := cfg.funcFlagValues()
+	cfg.flags.VisitAll(func(f *flag.Flag) {
+		switch f.Name {
+		case "config", "version", "p", "v":
+			return
+		}
+		var v any
+		if fv, ok := funcValues[f.Name]; ok {
+			if fv == nil {
+				return
+			}
+			v = fv
+		} else if g, ok := f.Value.(flag.Getter); ok {
+			switch g.Get().(type) {
+			case bool, int, int64, float64:
+				v = g.Get()
+			default:
+				v = f.Value.String()
+			}
+		} else {
+			v = f.Value.String()
+		}
+		values[f.Name] = redact(f.Name, v)
+	})
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		fmt.Println(err)
+		return exitError
+	}
+	fmt.Println(string(data))
+	return exitOK
+}
+
+// funcFlagValues 返回以 fs.Func 定义的参数的当前取值
+// 每次设置都会替换取值的参数输出为逗号分隔的字符串, 可重复指定的参数输出为数组
+func (c *Config) funcFlagValues() map[string]any {
+	targets := make([]map[string]any, 0, len(c.ProxyTestTargets))
+	for _, t := range c.ProxyTestTargets {
+		targets = append(targets, map[string]any{"url": t.url, "expectCode": t.expectCode})
+	}
+	var since any // 未设置时不输出, 空字符串不是合法的 -since 取值
+	if !c.Since.IsZero() {
+		since = c.Since.Format(time.RFC3339)
+	}
+	return map[string]any{
+		"asset":              strings.Join(c.Assets, ","),
+		"input-compressions": strings.Join(c.InputCompressions, ","),
+		"since":              since,
+		"proxy-test-url":     targets,
+		"proxy-candidate":    nonNil(c.ProxyCandidates),
+		"exclude-proxy":      nonNil(c.ExcludeProxies),
+		"proxy-allow":        nonNil(c.ProxyAllow),
+		"proxy-deny":         nonNil(c.ProxyDeny),
+		"mirror":             nonNil(c.Mirrors),
+	}
+}
+
+// nonNil 将 nil 切片转换为空切片, 使 JSON 输出为 [] 而不是 null
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// redact 隐藏 token 类参数的取值, 以及字符串中 URL 携带的密码
+func redact(name string, v any) any {
+	if secretFlags[name] {
+		if s, _ := v.(string); s != "" {
+			return "******"
+		}
+		return v
+	}
+	switch v := v.(type) {
+	case string:
+		return redactURL(v)
+	case []string:
+		out := make([]string, len(v))
+		for i, s := range v {
+			out[i] = redactURL(s)
+		}
+		return out
+	}
+	return v
+}
+
+// redactURL 将 URL 中的密码替换为 xxxxx, 不是 URL 时原样返回
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	return u.Redacted()
+}
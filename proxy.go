@@ -1,46 +1,202 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strconv"
 	"sync"
+	"text/tabwriter"
 	"time"
 )
 
+// defaultProxyCandidates 为内置的常见本地代理端口
+var defaultProxyCandidates = []string{
+	"http://127.0.0.1:7890",
+	"http://127.0.0.1:7891",
+	"http://127.0.0.1:1080",
+	"http://127.0.0.1:8080",
+	"http://127.0.0.1:10808",
+	"http://127.0.0.1:10809",
+}
+
+// normalizeProxy 将只写端口的候选代理补全为本地 http 代理地址
+func normalizeProxy(p string) string {
+	if _, err := strconv.Atoi(p); err == nil {
+		return "http://127.0.0.1:" + p
+	}
+	return p
+}
+
+// proxyCandidates 返回内置候选代理去掉 -exclude-proxy 后, 再加上 -proxy-candidate 的结果
+func proxyCandidates(cfg *Config) []string {
+	excluded := make(map[string]bool)
+	for _, p := range cfg.ExcludeProxies {
+		excluded[normalizeProxy(p)] = true
+	}
+	var candidates []string
+	for _, p := range slices.Concat(defaultProxyCandidates, cfg.ProxyCandidates, cfg.fileProxy) {
+		p = normalizeProxy(p)
+		if excluded[p] || slices.Contains(candidates, p) {
+			continue
+		}
+		if !proxyAllowed(cfg, p) {
+			if cfg.Verbose {
+				log.Printf(tr("候选代理 %s 不符合 -proxy-allow / -proxy-deny, 已跳过"), p)
+			}
+			continue
+		}
+		candidates = append(candidates, p)
+	}
+	return candidates
+}
+
+// proxyAllowed 判断代理是否允许使用: 匹配任一 -proxy-deny 时拒绝,
+// 指定了 -proxy-allow 时必须匹配其中之一
+func proxyAllowed(cfg *Config, proxy string) bool {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range cfg.ProxyDeny {
+		if matchProxy(pattern, u) {
+			return false
+		}
+	}
+	if len(cfg.ProxyAllow) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(cfg.ProxyAllow, func(pattern string) bool { return matchProxy(pattern, u) })
+}
+
+// matchProxy 判断代理地址是否匹配 pattern
+// pattern 为 CIDR (如 127.0.0.0/8) 时匹配代理主机的 IP, 否则作为通配符匹配 主机:端口 或 主机, 如 127.0.0.1:78*
+func matchProxy(pattern string, u *url.URL) bool {
+	if _, network, err := net.ParseCIDR(pattern); err == nil {
+		ip := net.ParseIP(u.Hostname())
+		return ip != nil && network.Contains(ip)
+	}
+	for _, s := range []string{u.Host, u.Hostname()} {
+		if ok, _ := path.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // isProxyAvailable 并发检测代理是否可用
-// 要求 Google 204 和 GitHub Raw 两个检测目标都成功
+// 要求 proxyTestTargets (可通过 -proxy-test-url 配置) 中的每个检测目标都返回预期的状态码
 func isProxyAvailable(proxy string) bool {
+	ok, _ := probeProxy(proxy)
+	return ok
+}
+
+// testTarget 为连通性检测目标及其期望的状态码
+type testTarget struct {
+	url        string
+	expectCode int
+}
+
+// proxyTestTargets 为检测代理时访问的目标, 须全部成功
+var proxyTestTargets = []testTarget{
+	{"https://www.google.com/generate_204", http.StatusNoContent},                           // 204
+	{"https://raw.githubusercontent.com/github/gitignore/main/Go.gitignore", http.StatusOK}, // 200
+}
+
+// proxyProbeAttempts 为判定代理不可用前的检测次数, 可通过 -proxy-probe-attempts 修改
+var proxyProbeAttempts = 1
+
+// proxyLatencySamples 为比较代理延迟时每个可用代理的采样次数, 可通过 -proxy-samples 修改
+var proxyLatencySamples = 1
+
+// proxyProbeRetryDelay 为同一代理两次检测之间的间隔
+const proxyProbeRetryDelay = 500 * time.Millisecond
+
+// directTestTargets 为检测直连时访问的目标
+// 直连只需要能访问 GitHub, 不要求 Google 可达
+var directTestTargets = []testTarget{
+	{"https://api.github.com/zen", http.StatusOK},
+	{"https://raw.githubusercontent.com/github/gitignore/main/Go.gitignore", http.StatusOK},
+}
+
+// probeProxy 检测代理是否可用, 同时返回两个检测目标全部完成所用的时间
+// 共尝试 proxyProbeAttempts 次, 避免短暂繁忙的代理因一次失败被丢弃; 延迟取成功那次的结果
+func probeProxy(proxy string) (bool, time.Duration) {
 	proxyURL, err := url.Parse(proxy)
 	if err != nil {
-		return false
+		return false, 0
 	}
+	var (
+		ok      bool
+		latency time.Duration
+	)
+	for i := range max(proxyProbeAttempts, 1) {
+		if i > 0 {
+			time.Sleep(proxyProbeRetryDelay)
+		}
+		if ok, latency = probeTargets(http.ProxyURL(proxyURL), proxyTestTargets); ok {
+			break
+		}
+	}
+	return ok, latency
+}
 
+// sampleLatency 对已检测可用的代理再采样 proxyLatencySamples-1 次, 返回包括首次在内所有成功采样的平均延迟
+// 单次请求的延迟容易受网络抖动影响, 取平均值使延迟排序更稳定; 采样失败不影响可用性判断
+func sampleLatency(proxy string, first time.Duration) time.Duration {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return first
+	}
+	total, n := first, 1
+	for range proxyLatencySamples - 1 {
+		if ok, latency := probeTargets(http.ProxyURL(proxyURL), proxyTestTargets); ok {
+			total += latency
+			n++
+		}
+	}
+	return total / time.Duration(n)
+}
+
+// isDirectAvailable 检测不使用代理时能否访问 GitHub
+func isDirectAvailable() bool {
+	ok, _ := probeTargets(nil, directTestTargets)
+	return ok
+}
+
+// probeTargets 通过 proxy (为 nil 时直连) 并发访问所有检测目标, 全部成功时返回 true
+func probeTargets(proxy func(*http.Request) (*url.URL, error), targets []testTarget) (bool, time.Duration) {
 	transport := &http.Transport{
-		Proxy: http.ProxyURL(proxyURL),
+		Proxy:           proxy,
+		TLSClientConfig: tlsConfig,
 	}
 	client := &http.Client{
 		Transport: transport,
 		Timeout:   3 * time.Second,
 	}
 
-	// 检测目标列表
-	testURLs := []struct {
-		url        string
-		expectCode int
-	}{
-		{"https://www.google.com/generate_204", http.StatusNoContent},                           // 204
-		{"https://raw.githubusercontent.com/github/gitignore/main/Go.gitignore", http.StatusOK}, // 200
-	}
-
 	var wg sync.WaitGroup
-	results := make(chan bool, len(testURLs))
+	results := make(chan bool, len(targets))
+	start := time.Now()
 
 	// 并发检测
-	for _, t := range testURLs {
+	for _, t := range targets {
 		wg.Add(1)
 		go func(target string, expect int) {
 			defer wg.Done()
-			resp, err := client.Get(target)
+			req, err := newRequest(http.MethodGet, target, nil)
+			if err != nil {
+				results <- false
+				return
+			}
+			resp, err := client.Do(req)
 			if err != nil {
 				results <- false
 				return
@@ -53,18 +209,41 @@ func isProxyAvailable(proxy string) bool {
 	// 等待所有检测完成
 	wg.Wait()
 	close(results)
+	latency := time.Since(start)
 
 	// 必须全部成功
 	for ok := range results {
 		if !ok {
-			return false
+			return false, latency
 		}
 	}
-	return true
+	return true, latency
 }
 
-// findAvailableProxy 优先检测配置文件中的代理，不可用则并发检测常见端口
-func findAvailableProxy(configProxy string, candidates []string) string {
+// findAvailableProxy 在 timeout 内查找可用代理, 超时后不再等待仍未返回的检测, 视为未找到可用代理
+// timeout 为 0 时不限制
+func findAvailableProxy(configProxy string, candidates []string, timeout time.Duration) string {
+	found := make(chan string, 1)
+	go func() { found <- detectProxy(configProxy, candidates) }()
+	select {
+	case proxy := <-found:
+		return proxy
+	case <-afterTimeout(timeout):
+		log.Printf(tr("代理检测超过 %s, 停止等待"), timeout)
+		return ""
+	}
+}
+
+// afterTimeout 返回 d 后触发的 channel, d 为 0 时返回永不触发的 nil channel
+func afterTimeout(d time.Duration) <-chan time.Time {
+	if d <= 0 {
+		return nil
+	}
+	return time.After(d)
+}
+
+// detectProxy 优先检测配置文件中的代理，不可用则并发检测常见端口
+func detectProxy(configProxy string, candidates []string) string {
 	// Step 1: 优先检测配置文件中的代理
 	if configProxy != "" && isProxyAvailable(configProxy) {
 		return configProxy
@@ -99,3 +278,203 @@ func findAvailableProxy(configProxy string, candidates []string) string {
 	}
 	return ""
 }
+
+// probeResult 为单个代理的检测结果
+type probeResult struct {
+	proxy   string
+	ok      bool
+	latency time.Duration
+}
+
+// probeAllProxies 并发检测所有候选代理, 结果按候选顺序返回, 可用代理的延迟为 -proxy-samples 次采样的平均值
+// 超过 timeout (为 0 时不限制) 仍未完成的代理按不可用处理
+func probeAllProxies(candidates []string, timeout time.Duration) []probeResult {
+	results := make([]probeResult, len(candidates))
+	for i, p := range candidates {
+		results[i].proxy = p
+	}
+	type indexed struct {
+		i int
+		probeResult
+	}
+	done := make(chan indexed, len(candidates))
+	for i, p := range candidates {
+		go func() {
+			ok, latency := probeProxy(p)
+			if ok && proxyLatencySamples > 1 {
+				latency = sampleLatency(p, latency)
+			}
+			done <- indexed{i, probeResult{proxy: p, ok: ok, latency: latency}}
+		}()
+	}
+	deadline := afterTimeout(timeout)
+	for range candidates {
+		select {
+		case r := <-done:
+			results[r.i] = r.probeResult
+		case <-deadline:
+			log.Printf(tr("代理检测超过 %s, 使用已完成的检测结果"), timeout)
+			return results
+		}
+	}
+	return results
+}
+
+// scanAllProxies 完整检测配置代理与全部候选代理, 不在找到第一个可用代理时提前结束
+// 配置代理可用时优先使用, 否则选择延迟最低的可用候选;
+// verbose 为真时输出结果表格, 否则逐行输出每个结果
+func scanAllProxies(configProxy string, candidates []string, verbose bool, timeout time.Duration) string {
+	all := make([]string, 0, len(candidates)+1)
+	seen := make(map[string]bool)
+	for _, p := range append([]string{configProxy}, candidates...) {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			all = append(all, p)
+		}
+	}
+
+	results := probeAllProxies(all, timeout)
+	var chosen *probeResult
+	for i := range results {
+		r := &results[i]
+		if !r.ok {
+			continue
+		}
+		if r.proxy == configProxy {
+			chosen = r
+			break
+		}
+		if chosen == nil || r.latency < chosen.latency {
+			chosen = r
+		}
+	}
+
+	if verbose {
+		printProbeTable(results, chosen)
+	} else {
+		for _, r := range results {
+			status := tr("不可用")
+			if r.ok {
+				status = tr("可用")
+			}
+			log.Printf(tr("代理 %s: %s"), r.proxy, status)
+		}
+	}
+	if chosen == nil {
+		return ""
+	}
+	return chosen.proxy
+}
+
+// printProbeTable 以表格形式输出代理检测结果
+func printProbeTable(results []probeResult, chosen *probeResult) {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, tr("代理\t结果\t延迟\t"))
+	for i := range results {
+		r := &results[i]
+		status := tr("失败")
+		if r.ok {
+			status = tr("通过")
+		}
+		mark := ""
+		if r == chosen {
+			mark = tr("<- 使用")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.proxy, status, r.latency.Round(time.Millisecond), mark)
+	}
+	tw.Flush()
+	log.Print(tr("代理检测结果:\n") + buf.String())
+}
+
+// setupProxy 选择可用的代理并设置 HTTP_PROXY / HTTPS_PROXY, 返回使用的代理, 直连时为空
+// 代理与直连均不可用时返回 ErrProxyUnavailable
+func setupProxy(cfg *Config) (string, error) {
+	commonProxies := proxyCandidates(cfg)
+	applyProxyConfig(cfg)
+
+	var proxy string
+	if cfg.ForceProxyScan || cfg.Verbose {
+		if cfg.ForceProxyScan {
+			log.Println(tr("强制完整扫描所有候选代理..."))
+		}
+		proxy = scanAllProxies(cfg.Proxy, commonProxies, cfg.Verbose, cfg.ProxyTimeout)
+	} else {
+		proxy = findAvailableProxy(cfg.Proxy, commonProxies, cfg.ProxyTimeout)
+	}
+	if proxy != "" {
+		if err := applyProxy(cfg.ProxyFor, proxy); err != nil {
+			return "", err
+		}
+		log.Println(tr("使用代理:"), proxy)
+		return proxy, nil
+	}
+	log.Println(tr("未找到可用代理，检测直连..."))
+	if !isDirectAvailable() {
+		return "", withKind(ErrProxyUnavailable, errors.New(tr("无法连接到 GitHub: 代理与直连均不可用, 请检查网络连接")))
+	}
+	log.Println(tr("直连可用，将不设置代理"))
+	return "", nil
+}
+
+// 代理的使用范围 (-proxy-for)
+const (
+	proxyForBoth     = "both"     // API 请求与资源下载都使用代理
+	proxyForAPI      = "api"      // 只有 API 请求使用代理, 资源下载直连
+	proxyForDownload = "download" // 只有资源下载使用代理, API 请求直连
+)
+
+// applyProxy 按 -proxy-for 设置代理: API 请求通过环境变量使用代理, 资源下载使用单独的 Transport
+// 只对一类请求使用代理时, 另一类请求直连, 直连不可用时给出警告
+func applyProxy(scope, proxy string) error {
+	if scope == proxyForBoth {
+		os.Setenv("HTTP_PROXY", proxy)
+		os.Setenv("HTTPS_PROXY", proxy)
+		return nil
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return err
+	}
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return errors.New(tr("无法为资源下载单独设置代理"))
+	}
+	transport := base.Clone()
+	if scope == proxyForAPI {
+		os.Setenv("HTTP_PROXY", proxy)
+		os.Setenv("HTTPS_PROXY", proxy)
+		transport.Proxy = nil
+	} else {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	downloadTransport = transport
+	log.Printf(tr("代理只用于 %s 请求, 其余请求直连"), scope)
+	if !isDirectAvailable() {
+		log.Println(tr("警告: 直连不可用, 不使用代理的请求可能失败"))
+	}
+	return nil
+}
+
+// runListProxies 检测配置代理、全部候选代理以及直连的可用性和延迟并输出结果后退出, 不下载任何文件
+// 用于确定应该使用哪个本地代理端口
+func runListProxies(cfg *Config) int {
+	applyProxyConfig(cfg)
+	proxy := scanAllProxies(cfg.Proxy, proxyCandidates(cfg), true, cfg.ProxyTimeout)
+	direct, latency := probeTargets(nil, directTestTargets)
+	if direct {
+		log.Printf(tr("直连: 可用 (%s)"), latency.Round(time.Millisecond))
+	} else {
+		log.Println(tr("直连: 不可用"))
+	}
+	switch {
+	case proxy != "":
+		log.Println(tr("建议使用代理:"), proxy)
+	case direct:
+		log.Println(tr("没有可用的代理, 可直接连接 GitHub"))
+	default:
+		log.Println(tr("无法连接到 GitHub: 代理与直连均不可用, 请检查网络连接"))
+		return exitProxyUnavailable
+	}
+	return exitOK
+}
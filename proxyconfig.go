@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// proxyConfigFile 是 subs-check / Clash 配置中与本地代理相关的字段
+type proxyConfigFile struct {
+	SystemProxy string `yaml:"system-proxy"` // subs-check
+	MixedPort   int    `yaml:"mixed-port"`   // Clash / mihomo
+	Port        int    `yaml:"port"`         // Clash http 端口
+}
+
+// applyProxyConfig 指定了 -proxy-config 时从中读取代理, 读取成功后覆盖 -proxy;
+// 最终的 -proxy 不符合 -proxy-allow / -proxy-deny 时不使用
+func applyProxyConfig(cfg *Config) {
+	if cfg.ProxyConfig != "" {
+		if p, err := proxyFromConfig(cfg.ProxyConfig); err != nil {
+			log.Printf(tr("读取代理配置失败, 将继续扫描候选代理: %v"), err)
+		} else {
+			log.Printf(tr("从 %s 读取到代理: %s"), cfg.ProxyConfig, p)
+			cfg.Proxy = p
+		}
+	}
+	if cfg.Proxy != "" && !proxyAllowed(cfg, cfg.Proxy) {
+		log.Printf(tr("配置的代理 %s 不符合 -proxy-allow / -proxy-deny, 不会使用"), cfg.Proxy)
+		cfg.Proxy = ""
+	}
+}
+
+// proxyFromConfig 从 subs-check 或 Clash 的 YAML 配置中读取本地代理地址
+// 依次使用 system-proxy、mixed-port、port
+func proxyFromConfig(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf(tr("读取代理配置 %s 失败: %w"), path, err)
+	}
+	var c proxyConfigFile
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return "", fmt.Errorf(tr("解析代理配置 %s 失败: %w"), path, err)
+	}
+	switch {
+	case c.SystemProxy != "":
+		if err := validateProxyURL(c.SystemProxy); err != nil {
+			return "", err
+		}
+		return c.SystemProxy, nil
+	case c.MixedPort > 0:
+		return "http://127.0.0.1:" + strconv.Itoa(c.MixedPort), nil
+	case c.Port > 0:
+		return "http://127.0.0.1:" + strconv.Itoa(c.Port), nil
+	}
+	return "", errors.New(tr("配置中没有 system-proxy、mixed-port 或 port"))
+}
+
+// readProxyFile 读取 -proxy-file 中的候选代理: 每行一个, 忽略空行和 # 开头的注释, 重复的地址只保留一个
+// 任一行无效时返回包含行号的错误
+func readProxyFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(tr("读取 -proxy-file %s 失败: %w"), path, err)
+	}
+	var (
+		proxies []string
+		errs    []error
+	)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := normalizeProxy(line)
+		if err := validateProxyURL(p); err != nil {
+			errs = append(errs, fmt.Errorf(tr("%s 第 %d 行: %w"), path, i+1, err))
+			continue
+		}
+		if !slices.Contains(proxies, p) {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies, errors.Join(errs...)
+}
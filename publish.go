@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// outputFile 是产物中需要写入目标目录的单个文件
+type outputFile struct {
+	path   string
+	data   []byte
+	asset  *ReleaseAsset  // 生成该文件的 release 资源
+	source downloadSource // 资源内容的实际来源
+
+	corrupt bool // 目标目录中的现有文件已损坏, 需要强制替换
+}
+
+// artifact 描述一个待写入目标目录的产物, 其中的文件在同一次提交中更新
+type artifact struct {
+	component string // 提交信息中使用的组件名
+	name      string // 日志中使用的名称
+	tag       string
+	files     []outputFile
+}
+
+// changedFiles 返回内容与目标目录中现有文件不同的文件
+func (a *artifact) changedFiles() []outputFile {
+	var changed []outputFile
+	for _, f := range a.files {
+		// 只在新内容本身是 zstd 时才判断, 避免自定义 Compressor 导致每次都强制替换
+		if bytes.HasPrefix(f.data, zstdMagic) && invalidZstd(f.path) {
+			log.Printf(tr("现有文件 %s 不是有效的 zstd 文件, 将强制替换"), f.path)
+			f.corrupt = true
+			changed = append(changed, f)
+			continue
+		}
+		currentHash, err := fileHash(f.path)
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf(tr("无法计算当前%s文件哈希: %v"), a.name, err)
+		}
+		if !bytes.Equal(currentHash, hashSum(f.data)) {
+			changed = append(changed, f)
+		}
+	}
+	return changed
+}
+
+// zstdMagic 是 zstd 帧开头的魔数
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// invalidZstd 判断 path 是否为已存在但开头不是 zstd 魔数的 .zst 文件 (如误提交的 HTML 错误页)
+func invalidZstd(path string) bool {
+	if !strings.HasSuffix(path, ".zst") {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, len(zstdMagic))
+	n, _ := io.ReadFull(f, head)
+	return !bytes.Equal(head[:n], zstdMagic)
+}
+
+// staleMetadata 返回内容未变化但元数据缺失或过期的文件
+func (a *artifact) staleMetadata(cfg *Config, changed []outputFile) []outputFile {
+	var stale []outputFile
+	for _, f := range a.files {
+		if containsPath(changed, f.path) {
+			continue
+		}
+		if metadataStale(f.path, a.metadata(cfg, f)) {
+			stale = append(stale, f)
+		}
+	}
+	return stale
+}
+
+// containsPath 判断 files 中是否包含路径为 path 的文件
+func containsPath(files []outputFile, path string) bool {
+	return slices.ContainsFunc(files, func(f outputFile) bool { return f.path == path })
+}
+
+// metadata 生成文件对应的元数据
+func (a *artifact) metadata(cfg *Config, f outputFile) *Metadata {
+	meta := newMetadata(a.component, a.tag, f.asset, f.source, f.path, f.data)
+	meta.Path = cfg.metadataRef(f.path)
+	return meta
+}
+
+// publish 比较哈希, 有变化时写入目标文件和元数据并提交;
+// 文件未变化但元数据缺失或过期时 (-sync-metadata) 只更新并提交元数据
+// 返回值表示是否存在更新; 检查模式下只比较, 不产生任何副作用
+func publish(cfg *Config, st *State, gitDir string, a *artifact) (bool, error) {
+	if cfg.Output == stdoutOutput {
+		return true, writeStdout(a)
+	}
+	changed := a.changedFiles()
+	var stale []outputFile
+	if cfg.SyncMetadata {
+		stale = a.staleMetadata(cfg, changed)
+	}
+	if len(changed) == 0 && len(stale) == 0 {
+		log.Printf(tr("%s文件已是最新，无需更新。"), a.name)
+		report.addArtifact(a, nil, false, false, nil)
+		return false, nil
+	}
+
+	if cfg.DryRun {
+		// 只有元数据需要同步时不算作可用更新, 避免 check 因此返回 exitUpdateAvailable
+		if len(changed) == 0 {
+			log.Printf(tr("%s文件已是最新, 但元数据需要同步 (检查模式, 不做任何修改)"), a.name)
+			report.addArtifact(a, nil, false, false, nil)
+			return false, nil
+		}
+		log.Printf(tr("%s文件有可用更新: %s (检查模式, 不做任何修改)"), a.name, a.tag)
+		if !cfg.NoCommit {
+			var summary []string
+			for _, f := range changed {
+				summary = append(summary, diffFile(f, cfg.DiffLines).String())
+			}
+			msg := commitParagraphs(cfg, a.component, a.tag, strings.Join(summary, "\n"), a.trailers(changed))
+			log.Printf(tr("提交信息预览 (检查模式, 不会提交):\n%s"), strings.Join(msg, "\n\n"))
+		}
+		report.addArtifact(a, changed, false, false, nil)
+		if cfg.DryRunGit && !cfg.NoCommit {
+			var paths []string
+			for _, f := range changed {
+				paths = append(paths, f.path)
+			}
+			for _, f := range append(changed, stale...) {
+				paths = append(paths, metadataPath(f.path))
+			}
+			if isBareRepo(gitDir) {
+				// 裸仓库没有可供 git add --dry-run 的工作区, 只检查提交路径
+				rels, err := treePaths(cfg, paths)
+				if err != nil {
+					return true, fmt.Errorf(tr("%s git 操作校验失败: %w"), a.name, err)
+				}
+				log.Printf(tr("%s 为裸仓库, 将在工作树中提交: %s"), gitDir, strings.Join(rels, ", "))
+			} else if err := dryRunGitCommands(cfg, gitDir, relPaths(gitDir, paths...), a.tag, a.component); err != nil {
+				return true, fmt.Errorf(tr("%s git 操作校验失败: %w"), a.name, err)
+			}
+		}
+		return true, nil
+	}
+
+	corrupt := slices.ContainsFunc(changed, func(f outputFile) bool { return f.corrupt })
+	if wait := st.commitWait(a.component, cfg.MinCommitInterval); wait > 0 && !corrupt {
+		log.Printf(tr("距离上次%s提交不足 %s, 将在 %s 后再更新"), a.name, cfg.MinCommitInterval, wait.Round(time.Second))
+		return false, nil
+	}
+
+	if err := checkFreeSpace(cfg, filepath.Dir(a.files[0].path), changed); err != nil {
+		return false, err
+	}
+	if err := checkSizeChange(cfg, changed); err != nil {
+		if !cfg.Force {
+			return false, err
+		}
+		log.Printf(tr("警告: %v (已指定 -force, 继续更新)"), err)
+	}
+
+	var (
+		written []string
+		summary []string
+	)
+	if len(changed) > 0 {
+		log.Printf(tr("%s文件有更新，准备替换..."), a.name)
+	}
+	for _, f := range changed {
+		d := diffFile(f, cfg.DiffLines).String()
+		log.Println(tr("变化:"), d)
+		summary = append(summary, d)
+	}
+	for _, f := range changed {
+		if err := replaceFile(f.path, f.data, cfg.fileMode()); err != nil {
+			return false, fmt.Errorf(tr("写入%s文件失败: %w"), a.name, err)
+		}
+		log.Printf(tr("已将%s文件更新到: %s"), a.name, f.path)
+		written = append(written, f.path)
+	}
+	for _, f := range append(changed, stale...) {
+		metaPath, err := writeMetadata(f.path, a.metadata(cfg, f))
+		if err != nil {
+			return false, fmt.Errorf(tr("写入%s元数据失败: %w"), a.name, err)
+		}
+		written = append(written, metaPath)
+	}
+	if len(stale) > 0 {
+		log.Printf(tr("已同步 %d 个%s元数据文件"), len(stale), a.name)
+	}
+
+	// git 提交与上传相互独立, 任一失败不影响另一项的执行
+	var errs []error
+	var (
+		committed, pushed bool
+		changes           []fileChange
+	)
+	if cfg.NoCommit {
+		log.Println(tr("已跳过 git 提交 (-no-commit)"))
+	} else {
+		var err error
+		changes, err = commitArtifact(cfg, st, gitDir, a, written, strings.Join(summary, "\n"), a.trailers(changed))
+		// 所有文件都与 HEAD 相同时不会产生提交
+		staged := slices.ContainsFunc(changes, func(c fileChange) bool { return c.Status != fileUnchanged })
+		committed = staged && (err == nil || errors.Is(err, errPushFailed))
+		pushed = staged && err == nil && cfg.Push
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	report.addArtifact(a, changed, committed, pushed, changes)
+
+	if cfg.UploadURL != "" && errors.Is(errors.Join(errs...), errVerifyFailed) {
+		log.Printf(tr("%s未通过提交后校验, 跳过上传"), a.name)
+	} else if cfg.UploadURL != "" {
+		for _, f := range changed {
+			if err := uploadArtifact(cfg.UploadURL, cfg.UploadToken, filepath.Base(f.path), f.data); err != nil {
+				errs = append(errs, fmt.Errorf(tr("%s文件上传失败: %w"), a.name, err))
+			} else {
+				log.Printf(tr("%s文件上传成功: %s"), a.name, cfg.UploadURL)
+			}
+		}
+	}
+	return true, errors.Join(errs...)
+}
+
+// stdoutOutput 为 -o 中表示标准输出的取值
+const stdoutOutput = "-"
+
+// writeStdout 将产物写到标准输出; 只允许单个文件, 以免多个文件拼接在一起
+func writeStdout(a *artifact) error {
+	if len(a.files) != 1 {
+		return fmt.Errorf(tr("-o - 只能输出单个文件, 当前%s产物有 %d 个文件, 可调整 -asset 或 -format"), a.name, len(a.files))
+	}
+	f := a.files[0]
+	if _, err := os.Stdout.Write(f.data); err != nil {
+		return fmt.Errorf(tr("写入标准输出失败: %w"), err)
+	}
+	log.Printf(tr("已将 %s 写到标准输出 (%s)"), filepath.Base(f.path), formatSize(int64(len(f.data))))
+	return nil
+}
+
+// replaceFile 先将 data 写入同目录的临时文件, 设置权限后重命名替换 path
+// path 已存在时沿用其权限 (以及 unix 下的属主), 否则使用 mode
+func replaceFile(path string, data []byte, mode os.FileMode) error {
+	existing, err := os.Stat(path)
+	if err == nil {
+		mode = existing.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	// -path-template 可能将文件放在尚不存在的子目录中
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".update-sub-store-*")
+	if err != nil {
+		return err
+	}
+	trackTemp(tmp.Name())
+	defer func() {
+		os.Remove(tmp.Name())
+		untrackTemp(tmp.Name())
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	if existing != nil {
+		copyOwner(tmp.Name(), existing)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// metadataOverhead 为估算所需空间时每个文件额外计入的元数据大小
+const metadataOverhead = 4 << 10
+
+// checkFreeSpace 在写入前确认 dir 所在文件系统能容纳 files 并保留 -min-free-mb 的余量
+func checkFreeSpace(cfg *Config, dir string, files []outputFile) error {
+	if cfg.MinFreeMB == 0 {
+		return nil
+	}
+	free, ok, err := freeSpace(dir)
+	if !ok {
+		return nil
+	}
+	if err != nil {
+		log.Printf(tr("无法获取 %s 的可用空间: %v"), dir, err)
+		return nil
+	}
+	need := cfg.MinFreeMB << 20
+	for _, f := range files {
+		need += int64(len(f.data)) + metadataOverhead
+	}
+	if free < need {
+		return fmt.Errorf(tr("%s 可用空间不足: 剩余 %s, 至少需要 %s (含 -min-free-mb %d MiB 余量)"), dir, formatSize(free), formatSize(need), cfg.MinFreeMB)
+	}
+	return nil
+}
+
+// trailers 返回记录版本和文件 sha256 的提交 trailer, 便于从 git 历史中查询各版本对应的提交
+func (a *artifact) trailers(changed []outputFile) []string {
+	trailers := []string{"Sub-Store-Version: " + a.tag}
+	for _, f := range changed {
+		trailers = append(trailers, fmt.Sprintf("Bundle-SHA256: %x", sha256.Sum256(f.data)))
+	}
+	return trailers
+}
+
+// checkSizeChange 与元数据中记录的上次大小比较, 任一文件的大小变化超过 -max-size-change 百分比时拒绝更新
+// 大小剧烈变化通常意味着资源选择错误或内容损坏; 没有元数据或现有文件已损坏时不检查
+func checkSizeChange(cfg *Config, changed []outputFile) error {
+	if cfg.MaxSizeChange <= 0 {
+		return nil
+	}
+	for _, f := range changed {
+		if f.corrupt {
+			continue
+		}
+		prev, err := readMetadata(f.path)
+		if err != nil || prev.FileSize <= 0 {
+			continue
+		}
+		delta := float64(int64(len(f.data))-prev.FileSize) / float64(prev.FileSize) * 100
+		if math.Abs(delta) > cfg.MaxSizeChange {
+			return fmt.Errorf(tr("%s 的大小从 %s 变为 %s (%+.1f%%), 超过 -max-size-change %g%%, 可能是资源有误; 确认无误后可加上 -force 更新"),
+				f.path, formatSize(prev.FileSize), formatSize(int64(len(f.data))), delta, cfg.MaxSizeChange)
+		}
+		if cfg.Verbose {
+			log.Printf(tr("%s 的大小变化 %+.1f%%"), f.path, delta)
+		}
+	}
+	return nil
+}
+
+// commitArtifact 在 git 目录中提交已写入的文件，并记录提交时间, body 为提交信息正文
+// 返回各文件在提交中的状态
+func commitArtifact(cfg *Config, st *State, gitDir string, a *artifact, paths []string, body string, trailers []string) ([]fileChange, error) {
+	changes, err := commitPending(cfg, st, gitDir, a.component, PendingCommit{Tag: a.tag, Paths: paths, Body: body, Trailers: trailers})
+	if err != nil {
+		return changes, fmt.Errorf(tr("%s git 操作失败: %w"), a.name, err)
+	}
+	return changes, nil
+}
+
+// commitPending 提交一次已写入的更新, 提交失败时将其保留在状态文件中, 之后可用 commit-only 重新提交
+func commitPending(cfg *Config, st *State, gitDir, component string, p PendingCommit) ([]fileChange, error) {
+	st.Pending[component] = p
+	var changes []fileChange
+	err := withWorktree(cfg, gitDir, p.Paths, func(dir string, rels []string) error {
+		var err error
+		changes, err = runGitCommands(cfg, dir, rels, p.Tag, component, p.Body, p.Trailers)
+		return err
+	})
+	if err == nil || errors.Is(err, errPushFailed) {
+		// 推送失败时提交已在本地完成, 同样记录提交时间; 文件与 HEAD 相同时没有产生提交, 不影响 -min-commit-interval
+		delete(st.Pending, component)
+		if slices.ContainsFunc(changes, func(c fileChange) bool { return c.Status != fileUnchanged }) {
+			st.LastCommit[component] = time.Now()
+		}
+	} else if errors.Is(err, errVerifyFailed) {
+		// 回滚已恢复原有文件, 没有需要重新提交的内容
+		delete(st.Pending, component)
+	} else {
+		log.Println(tr("文件已写入但未提交, 可稍后运行 update-sub-store commit-only 重新提交"))
+	}
+	if err := st.save(cfg.StateFile); err != nil {
+		log.Printf(tr("保存状态文件失败: %v"), err)
+	}
+	return changes, err
+}
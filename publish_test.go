@@ -0,0 +1,144 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"text/template"
+)
+
+func TestReplaceFileCreatesNestedDirs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "assets", "sub-store", "v2.19.0", "sub-store.bundle.js")
+	if err := replaceFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatalf("replaceFile: %v", err)
+	}
+	if err := replaceFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatalf("replaceFile 覆盖已有文件: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "v2" {
+		t.Fatalf("ReadFile = %q, %v", data, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("目录中残留了临时文件: %v", entries)
+	}
+}
+
+func TestNestedOutputPaths(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "subs-check")
+	dest := filepath.Join(repo, "public", "assets")
+	cfg := &Config{
+		RepoPath:        repo,
+		DestDir:         dest,
+		MetaStripPrefix: "public/",
+		MetaPrefix:      "static",
+		pathTmpl:        template.Must(template.New("path").Parse("{{.Component}}/{{.Tag}}/{{.Name}}")),
+	}
+	path, err := cfg.outputPath(dest, "sub-store", "v2.19.0", "sub-store.bundle.js.zst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dest, "sub-store", "v2.19.0", "sub-store.bundle.js.zst"); path != want {
+		t.Errorf("outputPath = %q, want %q", path, want)
+	}
+	if err := replaceFile(path, []byte("zst"), 0644); err != nil {
+		t.Fatalf("replaceFile: %v", err)
+	}
+
+	// 提交的路径相对于仓库根目录, 元数据中的路径另行去掉和添加前缀
+	if got, want := relPaths(repo, path), []string{filepath.Join("public", "assets", "sub-store", "v2.19.0", "sub-store.bundle.js.zst")}; !slices.Equal(got, want) {
+		t.Errorf("relPaths = %q, want %q", got, want)
+	}
+	if got, want := cfg.metadataRef(path), "static/assets/sub-store/v2.19.0/sub-store.bundle.js.zst"; got != want {
+		t.Errorf("metadataRef = %q, want %q", got, want)
+	}
+
+	cfg.pathTmpl = template.Must(template.New("path").Parse("../{{.Name}}"))
+	if _, err := cfg.outputPath(dest, "sub-store", "v2.19.0", "sub-store.bundle.js"); err == nil {
+		t.Error("渲染到 -dest 之外的模板应返回错误")
+	}
+}
+
+// testGitRepo 创建带一个初始提交的 git 仓库, 环境中没有 git 时跳过测试
+func testGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("没有 git")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "test"},
+		{"config", "user.email", "test@example.com"},
+		{"commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if _, err := runGit(dir, "git "+args[0], args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCommitPendingLastCommit(t *testing.T) {
+	repo := testGitRepo(t)
+	cfg := &Config{StateFile: filepath.Join(t.TempDir(), "state.json")}
+	st, err := loadState(cfg.StateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(repo, "assets", "sub-store.bundle.js")
+	if err := replaceFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := PendingCommit{Tag: "v1.0.0", Paths: []string{path}}
+
+	if _, err := commitPending(cfg, st, repo, "sub-store", p); err != nil {
+		t.Fatalf("commitPending: %v", err)
+	}
+	first, ok := st.LastCommit["sub-store"]
+	if !ok {
+		t.Fatal("产生提交后应记录提交时间")
+	}
+
+	// 文件与 HEAD 相同, 不会产生提交, 也不应刷新 -min-commit-interval 使用的提交时间
+	if _, err := commitPending(cfg, st, repo, "sub-store", p); err != nil {
+		t.Fatalf("commitPending: %v", err)
+	}
+	if got := st.LastCommit["sub-store"]; !got.Equal(first) {
+		t.Errorf("没有产生提交时 LastCommit 从 %v 变为 %v", first, got)
+	}
+	if _, ok := st.Pending["sub-store"]; ok {
+		t.Error("没有需要提交的内容时应清除待提交记录")
+	}
+}
+
+func TestPublishDryRunMetadataOnly(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub-store.bundle.js")
+	if err := os.WriteFile(path, []byte("bundle"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a := &artifact{component: "sub-store", name: "后端", tag: "v1.0.0", files: []outputFile{
+		{path: path, data: []byte("bundle"), asset: &ReleaseAsset{Name: "sub-store.bundle.js"}},
+	}}
+	// 元数据文件不存在, 但文件内容与目标目录一致
+	cfg := &Config{DryRun: true, NoCommit: true, SyncMetadata: true, RepoPath: dir}
+	updated, err := publish(cfg, &State{}, dir, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated {
+		t.Error("只有元数据需要同步时检查模式不应报告可用更新")
+	}
+	if fileExists(metadataPath(path)) {
+		t.Error("检查模式不应写入元数据")
+	}
+
+	a.files[0].data = []byte("new bundle")
+	if updated, err = publish(cfg, &State{}, dir, a); err != nil || !updated {
+		t.Errorf("文件内容变化时 publish = %v, %v, want true", updated, err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// minRateLimitWait 为速率限制等待的下限, 重置时间已过 (或时钟偏差导致为负) 时至少等待该时间
+	minRateLimitWait = time.Second
+	// maxRateLimitWait 为速率限制等待的上限, GitHub 的限额每小时重置一次, 更长的等待说明时间计算有误
+	maxRateLimitWait = time.Hour
+	// clockSkewTolerance 为本地时钟与响应 Date 头相差超过该值时视为时钟偏差
+	clockSkewTolerance = 30 * time.Second
+)
+
+// rateLimitError 表示请求因速率限制失败, wait 为距离限额重置的时间
+type rateLimitError struct {
+	error
+	wait time.Duration
+}
+
+func (e rateLimitError) Unwrap() error { return e.error }
+
+// rateLimitWait 根据 X-RateLimit-Reset 计算距离限额重置的时间, 响应不是速率限制时返回 false
+// 重置时间是 Unix 时间戳, 本地时钟不准时直接相减会得到负数或过长的等待;
+// 因此在本地时钟与响应 Date 头相差较大时改以服务器时间为准, 并将结果限制在合理范围内
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	now := time.Now()
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		if skew := now.Sub(date); skew.Abs() > clockSkewTolerance {
+			log.Printf(tr("警告: 本地时钟与 GitHub 服务器相差 %s, 将按服务器时间计算速率限制的重置时间"), skew.Round(time.Second))
+			now = date
+		}
+	}
+	wait := time.Unix(reset, 0).Sub(now)
+	switch {
+	case wait < minRateLimitWait:
+		wait = minRateLimitWait
+	case wait > maxRateLimitWait:
+		log.Printf(tr("警告: 速率限制的重置时间 %s 后超出合理范围, 按 %s 处理"), wait.Round(time.Second), maxRateLimitWait)
+		wait = maxRateLimitWait
+	}
+	return wait, true
+}
+
+// rateLimited 在响应为速率限制时返回附带等待时间的错误, 否则返回 nil
+func rateLimited(desc string, resp *http.Response) error {
+	wait, ok := rateLimitWait(resp)
+	if !ok {
+		return nil
+	}
+	return rateLimitError{fmt.Errorf(tr("%s: %s (已达到速率限制, %s 后重置)"), desc, resp.Status, wait.Round(time.Second)), wait}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// githubRemote 匹配 GitHub 仓库的远程地址, 支持 https、ssh 与 scp 形式
+var githubRemote = regexp.MustCompile(`^(?:https://(?:[^@/]+@)?github\.com/|ssh://git@github\.com/|git@github\.com:)([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// githubRepoFromRemote 从远程地址中解析 owner/repo, 不是 GitHub 仓库时返回错误
+func githubRepoFromRemote(remote string) (string, error) {
+	m := githubRemote.FindStringSubmatch(strings.TrimSpace(remote))
+	if m == nil {
+		return "", fmt.Errorf(tr("origin (%s) 不是 GitHub 仓库, 无法通过 API 确认推送结果"), redactURL(strings.TrimSpace(remote)))
+	}
+	return m[1] + "/" + m[2], nil
+}
+
+// verifyPushed 通过 GitHub API 确认远程分支的最新提交与本地 HEAD 一致,
+// 用于发现推送看似成功但远程没有更新的情况 (如分支保护规则拒绝)
+func verifyPushed(cfg *Config, gitDir string) error {
+	local, err := runGit(gitDir, "git rev-parse", "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	local = strings.TrimSpace(local)
+	remote, err := runGit(gitDir, "git remote get-url", "remote", "get-url", "origin")
+	if err != nil {
+		return err
+	}
+	repo, err := githubRepoFromRemote(remote)
+	if err != nil {
+		return err
+	}
+
+	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/commits/%s", repo, url.PathEscape(cfg.Branch))
+	err = cfg.Retry.do(tr("确认远程分支 "), func() error {
+		req, err := newGitHubRequest(http.MethodGet, apiURL)
+		if err != nil {
+			return err
+		}
+		// 该媒体类型只返回提交的 SHA
+		req.Header.Set("Accept", "application/vnd.github.sha")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return statusError(tr("GitHub API 请求失败"), resp)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+		if err != nil {
+			return err
+		}
+		// 远程可能稍有延迟, 不一致时按重试策略再次确认
+		if sha := string(bytes.TrimSpace(body)); sha != local {
+			return fmt.Errorf(tr("远程分支 %s/%s 的最新提交为 %s, 与本地提交 %s 不一致"), repo, cfg.Branch, sha, local)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf(tr("已确认远程分支 %s/%s 包含提交 %s"), repo, cfg.Branch, local)
+	return nil
+}
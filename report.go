@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runReport 是 -report 输出的单次运行摘要
+type runReport struct {
+	mu sync.Mutex
+
+	Start      time.Time         `json:"start"`
+	Duration   string            `json:"duration"`
+	DryRun     bool              `json:"dryRun,omitempty"`
+	Proxy      string            `json:"proxy,omitempty"` // 直连时为空
+	Components []componentReport `json:"components"`
+	Warnings   []string          `json:"warnings,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// componentReport 为单个组件的检查结果
+type componentReport struct {
+	Component string       `json:"component"`
+	Tag       string       `json:"tag"`
+	Updated   bool         `json:"updated"`
+	Committed bool         `json:"committed"`
+	Pushed    bool         `json:"pushed"`
+	Files     []fileReport `json:"files,omitempty"`
+	Changes   []fileChange `json:"changes,omitempty"` // 提交时各文件相对 HEAD 的状态
+}
+
+// fileReport 为写入目标目录的单个文件
+type fileReport struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	Hash string `json:"hash"` // 算法:十六进制摘要
+}
+
+// report 为当前运行的摘要, 未指定 -report 时为 nil, 此时各记录方法不做任何事
+var report *runReport
+
+// reportLogWriter 在日志原样输出的同时, 将警告记录到当前运行的摘要中
+type reportLogWriter struct {
+	w io.Writer
+}
+
+func (r reportLogWriter) Write(p []byte) (int, error) {
+	line := strings.TrimSpace(string(p))
+	for _, prefix := range []string{"警告:", "warning:"} {
+		if i := strings.Index(line, prefix); i >= 0 {
+			report.warn(line[i:])
+			break
+		}
+	}
+	return r.w.Write(p)
+}
+
+// startReport 在指定了 -report 时开始记录一次运行, 守护模式下每次检查重新开始
+func startReport(cfg *Config, proxy string) {
+	if cfg.Report == "" {
+		return
+	}
+	report = &runReport{Start: time.Now(), DryRun: cfg.DryRun, Proxy: proxy}
+}
+
+func (r *runReport) warn(msg string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, msg)
+}
+
+// addArtifact 记录一个组件的结果, changed 为本次写入的文件, changes 为提交时各文件的状态
+func (r *runReport) addArtifact(a *artifact, changed []outputFile, committed, pushed bool, changes []fileChange) {
+	if r == nil {
+		return
+	}
+	c := componentReport{Component: a.component, Tag: a.tag, Updated: len(changed) > 0, Committed: committed, Pushed: pushed, Changes: changes}
+	for _, f := range changed {
+		c.Files = append(c.Files, fileReport{Path: f.path, Size: int64(len(f.data)), Hash: hashAlgo + ":" + hashHex(f.data)})
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Components = append(r.Components, c)
+}
+
+// writeReport 结束记录并将摘要写入 -report, 扩展名为 .md 时输出 Markdown, 否则输出 JSON
+// 写入失败只记录日志, 不影响运行结果
+func writeReport(cfg *Config, runErr error) {
+	r := report
+	if r == nil {
+		return
+	}
+	data, err := r.finish(cfg.Report, runErr)
+	if err != nil {
+		log.Printf(tr("生成运行摘要失败: %v"), err)
+		return
+	}
+	if err := os.WriteFile(cfg.Report, data, 0644); err != nil {
+		log.Printf(tr("写入运行摘要 %s 失败: %v"), cfg.Report, err)
+	}
+}
+
+// finish 记录运行时长和错误, 按 path 的扩展名生成摘要内容
+// 持有锁期间不写日志, 避免与 reportLogWriter 互相等待
+func (r *runReport) finish(path string, runErr error) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Duration = time.Since(r.Start).Round(time.Millisecond).String()
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return []byte(r.markdown()), nil
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	return append(data, '\n'), err
+}
+
+// markdown 以 Markdown 表格输出摘要, 便于附加到 CI 产物或 PR 中
+func (r *runReport) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# update-sub-store\n\n")
+	fmt.Fprintf(&b, "- start: %s\n- duration: %s\n", r.Start.Format(time.RFC3339), r.Duration)
+	proxy := r.Proxy
+	if proxy == "" {
+		proxy = "direct"
+	}
+	fmt.Fprintf(&b, "- proxy: %s\n", proxy)
+	if r.DryRun {
+		fmt.Fprintf(&b, "- dry run\n")
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&b, "- error: %s\n", r.Error)
+	}
+	if len(r.Components) > 0 {
+		fmt.Fprintf(&b, "\n| component | tag | updated | committed | pushed |\n| --- | --- | --- | --- | --- |\n")
+		for _, c := range r.Components {
+			fmt.Fprintf(&b, "| %s | %s | %t | %t | %t |\n", c.Component, c.Tag, c.Updated, c.Committed, c.Pushed)
+		}
+		var files []fileReport
+		for _, c := range r.Components {
+			files = append(files, c.Files...)
+		}
+		if len(files) > 0 {
+			fmt.Fprintf(&b, "\n| file | size | hash |\n| --- | --- | --- |\n")
+			for _, f := range files {
+				fmt.Fprintf(&b, "| %s | %s | `%s` |\n", f.Path, formatSize(f.Size), f.Hash)
+			}
+		}
+	}
+	if len(r.Warnings) > 0 {
+		fmt.Fprintf(&b, "\n## warnings\n\n")
+		for _, w := range r.Warnings {
+			fmt.Fprintf(&b, "- %s\n", w)
+		}
+	}
+	return b.String()
+}
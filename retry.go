@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy 描述失败重试的次数与指数退避策略
+type RetryPolicy struct {
+	Attempts  int           // 总尝试次数, 小于 1 时按 1 处理
+	BaseDelay time.Duration // 第一次重试前的等待时间, 之后每次翻倍
+	MaxDelay  time.Duration // 单次等待的上限
+	Jitter    float64       // 随机抖动比例 (0~1), 实际等待在 delay*(1±Jitter) 之间
+
+	rand  func() float64      // 随机源, 为空时使用 math/rand
+	sleep func(time.Duration) // 等待函数, 为空时使用 time.Sleep
+}
+
+// defaultRetryPolicy 返回默认重试策略: 共 3 次, 1s 起步, 最长 30s, 20% 抖动
+func defaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:  3,
+		BaseDelay: time.Second,
+		MaxDelay:  30 * time.Second,
+		Jitter:    0.2,
+	}
+}
+
+// permanentError 标记不应重试的错误
+type permanentError struct{ error }
+
+func (e permanentError) Unwrap() error { return e.error }
+
+// permanent 包装 err, 使 RetryPolicy.do 不再重试
+func permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// delay 返回第 n 次重试 (从 1 开始) 前的等待时间
+func (p RetryPolicy) delay(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.Jitter > 0 {
+		r := rand.Float64
+		if p.rand != nil {
+			r = p.rand
+		}
+		d += time.Duration((r()*2 - 1) * p.Jitter * float64(d))
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return max(d, 0)
+}
+
+// do 按策略执行 fn, 直到成功、遇到不可重试的错误或用尽次数
+// 遇到速率限制时按 rateLimitError 中的重置时间等待
+func (p RetryPolicy) do(desc string, fn func() error) error {
+	attempts := max(p.Attempts, 1)
+	sleep := time.Sleep
+	if p.sleep != nil {
+		sleep = p.sleep
+	}
+
+	var err error
+	for i := 1; ; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		var perm permanentError
+		if errors.As(err, &perm) || i >= attempts {
+			return err
+		}
+		d := p.delay(i)
+		// 速率限制在重置前重试没有意义: 重置时间超过单次等待上限时直接放弃, 否则等到重置
+		var limited rateLimitError
+		if errors.As(err, &limited) {
+			if p.MaxDelay > 0 && limited.wait > p.MaxDelay {
+				return err
+			}
+			d = max(d, limited.wait)
+		}
+		log.Printf(tr("%s失败 (第 %d/%d 次): %v, %s 后重试"), desc, i, attempts, err, d.Round(time.Millisecond))
+		sleep(d)
+	}
+}
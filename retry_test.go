@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+	var got []time.Duration
+	for n := 1; n <= 7; n++ {
+		got = append(got, p.delay(n))
+	}
+	want := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second}
+	if !slices.Equal(got, want) {
+		t.Errorf("delay = %v, want %v", got, want)
+	}
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	tests := []struct {
+		r    float64
+		n    int
+		want time.Duration
+	}{
+		{0, 1, 800 * time.Millisecond},
+		{0.5, 1, time.Second},
+		{1, 1, 1200 * time.Millisecond},
+		{0, 2, 1600 * time.Millisecond},
+		{1, 2, 2400 * time.Millisecond},
+		// 抖动后的结果仍不超过 MaxDelay
+		{1, 5, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second, Jitter: 0.2, rand: func() float64 { return tt.r }}
+		if got := p.delay(tt.n); got != tt.want {
+			t.Errorf("delay(%d) with rand=%v = %v, want %v", tt.n, tt.r, got, tt.want)
+		}
+	}
+
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second, Jitter: 0.2}
+	for range 100 {
+		if d := p.delay(1); d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("delay(1) = %v, 超出 ±20%% 抖动范围", d)
+		}
+	}
+}
+
+// recordSleeps 返回记录每次等待时间的 sleep 函数
+func recordSleeps(sleeps *[]time.Duration) func(time.Duration) {
+	return func(d time.Duration) { *sleeps = append(*sleeps, d) }
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	var sleeps []time.Duration
+	p := RetryPolicy{Attempts: 3, BaseDelay: time.Second, MaxDelay: 30 * time.Second, sleep: recordSleeps(&sleeps)}
+	calls := 0
+	err := p.do("测试", func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("临时错误")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("do = %v after %d calls, want nil after 3", err, calls)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second}; !slices.Equal(sleeps, want) {
+		t.Errorf("sleeps = %v, want %v", sleeps, want)
+	}
+
+	sleeps, calls = nil, 0
+	errFail := errors.New("一直失败")
+	if err := p.do("测试", func() error { calls++; return errFail }); !errors.Is(err, errFail) || calls != 3 {
+		t.Errorf("用尽次数后 do = %v after %d calls, want %v after 3", err, calls, errFail)
+	}
+}
+
+func TestRetryPolicyDoPermanent(t *testing.T) {
+	var sleeps []time.Duration
+	p := RetryPolicy{Attempts: 5, BaseDelay: time.Second, sleep: recordSleeps(&sleeps)}
+	calls := 0
+	errBad := errors.New("404")
+	err := p.do("测试", func() error { calls++; return permanent(errBad) })
+	if !errors.Is(err, errBad) || calls != 1 || len(sleeps) != 0 {
+		t.Errorf("permanent 错误应立即返回: err=%v calls=%d sleeps=%v", err, calls, sleeps)
+	}
+}
+
+func TestRetryPolicyDoRateLimit(t *testing.T) {
+	var sleeps []time.Duration
+	p := RetryPolicy{Attempts: 2, BaseDelay: time.Second, MaxDelay: time.Minute, sleep: recordSleeps(&sleeps)}
+	calls := 0
+	err := p.do("测试", func() error {
+		calls++
+		if calls == 1 {
+			return rateLimitError{errors.New("403"), 45 * time.Second}
+		}
+		return nil
+	})
+	if err != nil || !slices.Equal(sleeps, []time.Duration{45 * time.Second}) {
+		t.Errorf("速率限制应等到重置: err=%v sleeps=%v", err, sleeps)
+	}
+
+	sleeps, calls = nil, 0
+	limited := rateLimitError{errors.New("403"), 2 * time.Minute}
+	err = p.do("测试", func() error { calls++; return limited })
+	if !errors.Is(err, limited) || calls != 1 || len(sleeps) != 0 {
+		t.Errorf("重置时间超过 MaxDelay 时应直接放弃: err=%v calls=%d sleeps=%v", err, calls, sleeps)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// selfRepo 为本工具发布 release 的仓库, 可通过 -ldflags "-X main.selfRepo=owner/repo" 或 -self-repo 覆盖
+var selfRepo = "sinspired/update-sub-store-to-subs-check"
+
+// checksumsFile 为 goreleaser 等工具生成的汇总校验文件名
+const checksumsFile = "checksums.txt"
+
+// runSelfUpdate 下载本工具最新 release 中与当前系统和架构匹配的可执行文件,
+// 校验 sha256 后原子替换正在运行的程序
+func runSelfUpdate(cfg *Config) int {
+	var release *Release
+	err := cfg.Retry.do(fmt.Sprintf(tr("获取 %s release "), cfg.SelfRepo), func() error {
+		var err error
+		release, err = fetchLatestRelease(cfg.SelfRepo)
+		return err
+	})
+	if err != nil {
+		log.Printf(tr("获取 %s 最新 release 失败: %v"), cfg.SelfRepo, err)
+		return exitCode(err)
+	}
+	log.Printf(tr("当前版本: %s, 最新版本: %s"), version, release.TagName)
+	if sameTag(version, release.TagName) {
+		log.Println(tr("已是最新版本, 无需更新。"))
+		return exitOK
+	}
+	if cfg.DryRun {
+		log.Printf(tr("有可用的新版本 %s (检查模式, 不做任何修改)"), release.TagName)
+		return exitUpdateAvailable
+	}
+
+	asset := selfAsset(release)
+	if asset == nil {
+		log.Printf(tr("release %s 中没有适用于 %s/%s 的可执行文件"), release.TagName, runtime.GOOS, runtime.GOARCH)
+		return exitAssetNotFound
+	}
+	expected, err := expectedChecksum(cfg, release, asset)
+	if err == nil && expected == nil {
+		expected, err = checksumFromList(cfg, release, asset)
+	}
+	if err != nil {
+		log.Printf(tr("获取新版本校验和失败: %v"), err)
+		return exitCode(err)
+	}
+	if expected == nil {
+		log.Printf(tr("release %s 中没有 %s 的校验和, 为安全起见不自动替换"), release.TagName, asset.Name)
+		return exitError
+	}
+
+	data, _, err := downloadAsset(cfg, tr("新版本"), asset, expected)
+	if err != nil {
+		log.Println(err)
+		return exitCode(err)
+	}
+	data, err = selfBinary(asset.Name, data, cfg.MaxDownloadMB<<20)
+	if err != nil {
+		log.Println(err)
+		return exitError
+	}
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		log.Printf(tr("无法确定当前程序路径: %v"), err)
+		return exitError
+	}
+	if err := replaceExecutable(exe, data); err != nil {
+		log.Printf(tr("替换 %s 失败: %v"), exe, err)
+		return exitError
+	}
+	log.Printf(tr("已将 %s 更新到 %s"), exe, release.TagName)
+	return exitOK
+}
+
+// selfAsset 在 release 中查找适用于当前系统和架构的资源, 见 selfAssetFor
+func selfAsset(release *Release) *ReleaseAsset {
+	return selfAssetFor(release, runtime.GOOS, runtime.GOARCH)
+}
+
+// selfAssetFor 在 release 中查找文件名中同时含有 goos 与 goarch 两个独立片段的资源, 忽略校验和签名文件
+// 如 update-sub-store_linux_amd64、update-sub-store-windows-amd64.exe.gz;
+// 按 _ - . 切分后逐段比较, 避免 arm 匹配到 arm64
+func selfAssetFor(release *Release, goos, goarch string) *ReleaseAsset {
+	for i := range release.Assets {
+		a := &release.Assets[i]
+		name := strings.ToLower(a.Name)
+		if name == checksumsFile || sidecarAsset(name) {
+			continue
+		}
+		tokens := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == '.' })
+		if slices.Contains(tokens, goos) && slices.Contains(tokens, goarch) {
+			return a
+		}
+	}
+	return nil
+}
+
+// selfBinaryName 为 release 归档中可执行文件的名称 (Windows 上另加 .exe)
+const selfBinaryName = "update-sub-store"
+
+// selfBinary 从下载的资源中取出可执行文件: 先去掉 gz / bz2 / zst 压缩, 再从 tar 或 zip 归档中
+// 取出名为 update-sub-store(.exe) 的文件; 不是归档时原样返回. 解压后超过 limit 字节 (limit > 0 时) 时返回错误
+func selfBinary(name string, data []byte, limit int64) ([]byte, error) {
+	if base, ok := strings.CutSuffix(name, ".tgz"); ok {
+		name = base + ".tar.gz"
+	}
+	name, data, err := decompressInput(name, data, []string{"gz", "bz2", "zst"}, limit)
+	if err != nil {
+		return nil, err
+	}
+	isBinary := func(entry string) bool {
+		base := path.Base(entry)
+		return base == selfBinaryName || base == selfBinaryName+".exe"
+	}
+	switch lower := strings.ToLower(name); {
+	case strings.HasSuffix(lower, ".tar"):
+		r := tar.NewReader(bytes.NewReader(data))
+		for {
+			hdr, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf(tr("读取归档 %s 失败: %w"), name, err)
+			}
+			if hdr.Typeflag == tar.TypeReg && isBinary(hdr.Name) {
+				return io.ReadAll(r)
+			}
+		}
+	case strings.HasSuffix(lower, ".zip"):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf(tr("读取归档 %s 失败: %w"), name, err)
+		}
+		for _, f := range zr.File {
+			if f.Mode().IsRegular() && isBinary(f.Name) {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return readLimited(rc, limit)
+			}
+		}
+	default:
+		return data, nil
+	}
+	return nil, fmt.Errorf(tr("归档 %s 中没有 %s 可执行文件"), name, selfBinaryName)
+}
+
+// checksumFromList 从 release 的 checksums.txt 中读取资源的 sha256, 没有该文件或其中没有该资源时返回 nil
+func checksumFromList(cfg *Config, release *Release, asset *ReleaseAsset) ([]byte, error) {
+	list := findAsset(release, checksumsFile)
+	if list == nil {
+		return nil, nil
+	}
+	data, _, err := downloadAsset(cfg, tr("校验"), list, nil)
+	if err != nil {
+		return nil, err
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset.Name {
+			return hex.DecodeString(fields[0])
+		}
+	}
+	return nil, nil
+}
+
+// replaceExecutable 将 data 写入 exe 同目录的临时文件后替换 exe
+// 运行中的程序在 Windows 上不能被覆盖, 因此只在 Windows 上先将其重命名为 .old 再移入新文件
+func replaceExecutable(exe string, data []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".update-sub-store-*")
+	if err != nil {
+		return err
+	}
+	trackTemp(tmp.Name())
+	defer func() {
+		os.Remove(tmp.Name())
+		untrackTemp(tmp.Name())
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	// 同一文件系统内的 rename 是原子的, 其他系统上直接覆盖, 任何时刻 exe 都是完整的旧程序或新程序
+	if runtime.GOOS != "windows" {
+		return os.Rename(tmp.Name(), exe)
+	}
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		// 恢复原程序, 避免留下没有可执行文件的状态
+		return errors.Join(err, os.Rename(old, exe))
+	}
+	// Windows 上仍在运行的旧程序无法删除, 留待下次更新时清理
+	os.Remove(old)
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSelfAssetFor(t *testing.T) {
+	release := &Release{Assets: []ReleaseAsset{
+		{Name: "checksums.txt"},
+		{Name: "update-sub-store_linux_arm64.tar.gz"},
+		{Name: "update-sub-store_linux_arm64.tar.gz.asc"},
+		{Name: "update-sub-store_linux_arm.tar.gz.sig"},
+		{Name: "update-sub-store_linux_arm.tar.gz"},
+		{Name: "update-sub-store_linux_amd64v3.tar.gz"},
+		{Name: "update-sub-store-windows-amd64.exe.gz"},
+		{Name: "update-sub-store-windows-amd64.exe.gz.sha256"},
+	}}
+	tests := []struct {
+		goos, goarch, want string
+	}{
+		{"linux", "arm64", "update-sub-store_linux_arm64.tar.gz"},
+		{"linux", "arm", "update-sub-store_linux_arm.tar.gz"},
+		{"linux", "amd64", ""},
+		{"windows", "amd64", "update-sub-store-windows-amd64.exe.gz"},
+		{"darwin", "arm64", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if a := selfAssetFor(release, tt.goos, tt.goarch); a != nil {
+			got = a.Name
+		}
+		if got != tt.want {
+			t.Errorf("selfAssetFor(%s/%s) = %q, want %q", tt.goos, tt.goarch, got, tt.want)
+		}
+	}
+}
+
+func TestSelfBinary(t *testing.T) {
+	binary := []byte("\x7fELF binary")
+
+	var tarBuf bytes.Buffer
+	gz := gzip.NewWriter(&tarBuf)
+	tw := tar.NewWriter(gz)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{"README.md", []byte("readme")},
+		{"update-sub-store", binary},
+	} {
+		tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0755, Size: int64(len(f.data)), Typeflag: tar.TypeReg})
+		tw.Write(f.data)
+	}
+	tw.Close()
+	gz.Close()
+
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	w, _ := zw.Create("dist/update-sub-store.exe")
+	w.Write(binary)
+	zw.Close()
+
+	var plain bytes.Buffer
+	gz = gzip.NewWriter(&plain)
+	gz.Write(binary)
+	gz.Close()
+
+	for name, data := range map[string][]byte{
+		"update-sub-store_linux_amd64.tar.gz":   tarBuf.Bytes(),
+		"update-sub-store_linux_amd64.tgz":      tarBuf.Bytes(),
+		"update-sub-store_windows_amd64.zip":    zipBuf.Bytes(),
+		"update-sub-store_linux_amd64.gz":       plain.Bytes(),
+		"update-sub-store_linux_amd64":          binary,
+		"update-sub-store-windows-amd64.exe.gz": plain.Bytes(),
+	} {
+		got, err := selfBinary(name, data, 0)
+		if err != nil {
+			t.Errorf("selfBinary(%s): %v", name, err)
+			continue
+		}
+		if !bytes.Equal(got, binary) {
+			t.Errorf("selfBinary(%s) = %q, want %q", name, got, binary)
+		}
+	}
+
+	var empty bytes.Buffer
+	tw = tar.NewWriter(&empty)
+	tw.WriteHeader(&tar.Header{Name: "README.md", Mode: 0644, Typeflag: tar.TypeReg})
+	tw.Close()
+	if _, err := selfBinary("update-sub-store_linux_amd64.tar", empty.Bytes(), 0); err == nil {
+		t.Error("归档中没有可执行文件时应返回错误")
+	}
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "update-sub-store")
+	if err := os.WriteFile(exe, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceExecutable(exe, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil || string(data) != "new" {
+		t.Fatalf("ReadFile = %q, %v", data, err)
+	}
+	if info, _ := os.Stat(exe); runtime.GOOS != "windows" && info.Mode().Perm() != 0755 {
+		t.Errorf("Mode = %o, 应保留原程序的权限", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(dir)
+	if runtime.GOOS != "windows" && len(entries) != 1 {
+		t.Errorf("目录中残留了其他文件: %v", entries)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// State 记录跨次运行需要保留的信息
+type State struct {
+	LastCommit map[string]time.Time     `json:"last_commit"`
+	Pending    map[string]PendingCommit `json:"pending,omitempty"`  // 已写入但尚未成功提交的文件, 供 commit-only 使用
+	Sources    map[string]string        `json:"sources,omitempty"`  // 目标目录中各组件文件对应的源资源摘要, 见 sourceKey
+	LastRun    map[string]time.Time     `json:"last_run,omitempty"` // 各组件上次成功运行的开始时间, 供 -since-last-run 使用
+}
+
+// PendingCommit 是一次已写入目标目录、等待提交的更新
+type PendingCommit struct {
+	Tag      string   `json:"tag"`
+	Paths    []string `json:"paths"`
+	Body     string   `json:"body,omitempty"`
+	Trailers []string `json:"trailers,omitempty"`
+}
+
+// loadState 读取状态文件，文件不存在时返回空状态
+func loadState(path string) (*State, error) {
+	st := &State{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			st.init()
+			return st, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, err
+	}
+	st.init()
+	return st, nil
+}
+
+func (s *State) init() {
+	if s.LastCommit == nil {
+		s.LastCommit = make(map[string]time.Time)
+	}
+	if s.Pending == nil {
+		s.Pending = make(map[string]PendingCommit)
+	}
+	if s.Sources == nil {
+		s.Sources = make(map[string]string)
+	}
+	if s.LastRun == nil {
+		s.LastRun = make(map[string]time.Time)
+	}
+}
+
+// save 将状态写回文件
+func (s *State) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// commitWait 返回距离允许再次提交还需等待的时间，0 表示可以提交
+func (s *State) commitWait(component string, interval time.Duration) time.Duration {
+	last, ok := s.LastCommit[component]
+	if !ok || interval <= 0 {
+		return 0
+	}
+	if wait := interval - time.Since(last); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// sourceKey 由 API 声明的资源摘要和影响生成结果的参数组成, 相同时生成的文件必然相同
+// 任一资源没有摘要时返回空字符串, 表示无法判断
+func sourceKey(cfg *Config, assets []*ReleaseAsset) string {
+	parts := []string{
+		fmt.Sprintf("format=%s level=%s pattern=%s hash=%s", cfg.Format, cfg.Level, cfg.CompressPattern, cfg.HashAlgo),
+		fmt.Sprintf("path=%q input=%s sourcemap=%t/%q meta=%q/%q", cfg.PathTemplate, strings.Join(cfg.InputCompressions, ","),
+			cfg.IncludeSourcemap, cfg.SourcemapName, cfg.MetaPrefix, cfg.MetaStripPrefix),
+	}
+	for _, a := range assets {
+		digest := assetDigest(a)
+		if digest == "" {
+			return ""
+		}
+		parts = append(parts, a.Name+"="+digest)
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestSourceKey(t *testing.T) {
+	assets := []*ReleaseAsset{{Name: "sub-store.bundle.js", Digest: "sha256:ab"}}
+	base := func() *Config {
+		return &Config{Format: formatZst, Level: "default", CompressPattern: "*.js", HashAlgo: "sha256", InputCompressions: []string{"gz", "zst"}}
+	}
+	key := sourceKey(base(), assets)
+	if key == "" {
+		t.Fatal("资源有摘要时 sourceKey 不应为空")
+	}
+	if got := sourceKey(base(), assets); got != key {
+		t.Errorf("相同参数得到不同的 sourceKey:\n%s\n%s", key, got)
+	}
+
+	// 以下参数都会影响生成的文件或其路径, 变化时必须得到不同的 key
+	for name, change := range map[string]func(*Config){
+		"format":            func(c *Config) { c.Format = formatBoth },
+		"level":             func(c *Config) { c.Level = "best" },
+		"compress-pattern":  func(c *Config) { c.CompressPattern = "*" },
+		"hash-algo":         func(c *Config) { c.HashAlgo = "sha512" },
+		"path-template":     func(c *Config) { c.PathTemplate = "{{.Tag}}/{{.Name}}" },
+		"input-compression": func(c *Config) { c.InputCompressions = []string{"gz"} },
+		"include-sourcemap": func(c *Config) { c.IncludeSourcemap = true },
+		"sourcemap-name":    func(c *Config) { c.SourcemapName = "bundle.js.map" },
+		"metadata-prefix":   func(c *Config) { c.MetaPrefix = "static" },
+		"metadata-strip":    func(c *Config) { c.MetaStripPrefix = "public/" },
+	} {
+		cfg := base()
+		change(cfg)
+		if sourceKey(cfg, assets) == key {
+			t.Errorf("修改 %s 后 sourceKey 未变化", name)
+		}
+	}
+
+	if sourceKey(base(), append(assets, &ReleaseAsset{Name: "version.txt"})) != "" {
+		t.Error("任一资源没有摘要时 sourceKey 应为空")
+	}
+}
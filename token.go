@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// tokenCheckRepo 是校验 token 时访问的仓库, 读取其信息只需要最基本的元数据权限
+const tokenCheckRepo = "sub-store-org/Sub-Store"
+
+// validateToken 用一次轻量的认证请求检查 token 是否有效且可以读取上游仓库,
+// 以便在配置有误时尽早给出明确的错误, 而不是在后续流程中失败
+// 指定了 -push 且 origin 为 https 形式的 GitHub 仓库时, 还检查 token 能否推送到该仓库;
+// 通过 ssh 推送时 git 不使用 token, 不做检查
+func validateToken(cfg *Config, gitDir string) error {
+	if err := checkTokenRepo(cfg, tokenCheckRepo, false); err != nil {
+		return err
+	}
+	if !cfg.Push || cfg.NoCommit || cfg.DryRun {
+		return nil
+	}
+	remote, err := runGit(gitDir, "git remote get-url", "remote", "get-url", "origin")
+	if err != nil || !strings.HasPrefix(strings.TrimSpace(remote), "https://") {
+		return nil
+	}
+	repo, err := githubRepoFromRemote(remote)
+	if err != nil {
+		return nil
+	}
+	return checkTokenRepo(cfg, repo, true)
+}
+
+// checkTokenRepo 读取仓库 repo 的信息, push 为 true 时还要求 token 对其有推送权限
+// 响应中没有 permissions 字段时, 按经典 token 的 X-OAuth-Scopes 判断
+func checkTokenRepo(cfg *Config, repo string, push bool) error {
+	return cfg.Retry.do(tr("校验 GitHub token "), func() error {
+		req, err := newGitHubRequest(http.MethodGet, "https://api.github.com/repos/"+repo)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			// 经典 token 会返回其授权范围, 细粒度 token 不返回该响应头
+			scopes, hasScopes := resp.Header["X-Oauth-Scopes"]
+			if hasScopes && cfg.Verbose && !push {
+				log.Printf(tr("GitHub token 授权范围: %q"), scopes)
+			}
+			if !push {
+				return nil
+			}
+			var info struct {
+				Permissions *struct {
+					Push bool `json:"push"`
+				} `json:"permissions"`
+			}
+			if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&info); err != nil {
+				return fmt.Errorf(tr("解析仓库 %s 的信息失败: %w"), repo, err)
+			}
+			allowed := true
+			if info.Permissions != nil {
+				allowed = info.Permissions.Push
+			} else if hasScopes {
+				allowed = slices.ContainsFunc(scopes, func(v string) bool {
+					return slices.ContainsFunc(strings.Split(v, ","), func(s string) bool {
+						s = strings.TrimSpace(s)
+						return s == "repo" || s == "public_repo"
+					})
+				})
+			}
+			if !allowed {
+				return permanent(fmt.Errorf(tr("GitHub token 没有推送到 %s 的权限, 请为 token 授予该仓库的 contents 写权限"), repo))
+			}
+			return nil
+		case http.StatusUnauthorized:
+			return permanent(fmt.Errorf(tr("GitHub token 无效或已过期 (%s), 请检查 -token 或 GITHUB_TOKEN"), resp.Status))
+		case http.StatusForbidden, http.StatusNotFound:
+			if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+				return statusError(tr("GitHub API 请求失败"), resp)
+			}
+			err := fmt.Errorf(tr("GitHub token 没有读取 %s 的权限 (%s)"), repo, resp.Status)
+			// 细粒度 token 权限不足时, GitHub 会在响应头中说明所需的权限
+			if need := resp.Header.Get("X-Accepted-GitHub-Permissions"); need != "" {
+				err = fmt.Errorf(tr("%w, 需要的权限: %s"), err, need)
+			}
+			return permanent(err)
+		}
+		return statusError(tr("GitHub API 请求失败"), resp)
+	})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// rewriteTransport 将所有请求转发到测试服务器
+type rewriteTransport struct{ target *url.URL }
+
+func (rt rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = rt.target.Scheme, rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestValidateTokenPushPermission(t *testing.T) {
+	repo := testGitRepo(t)
+	var destBody, destScopes string
+	var destRequested bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/" + tokenCheckRepo:
+			w.Write([]byte(`{}`))
+		case "/repos/owner/dest":
+			destRequested = true
+			if destScopes != "" {
+				w.Header().Set("X-OAuth-Scopes", destScopes)
+			}
+			w.Write([]byte(destBody))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+	prev := http.DefaultClient.Transport
+	http.DefaultClient.Transport = rewriteTransport{target}
+	t.Cleanup(func() { http.DefaultClient.Transport = prev })
+
+	setOrigin := func(remote string) {
+		t.Helper()
+		runGit(repo, "git remote remove", "remote", "remove", "origin")
+		if _, err := runGit(repo, "git remote add", "remote", "add", "origin", remote); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &Config{Push: true, Retry: RetryPolicy{Attempts: 1}}
+
+	setOrigin("https://github.com/owner/dest.git")
+	tests := []struct {
+		name, body, scopes string
+		ok                 bool
+	}{
+		{"有推送权限", `{"permissions":{"push":true}}`, "", true},
+		{"没有推送权限", `{"permissions":{"pull":true,"push":false}}`, "", false},
+		{"经典 token 有 repo 范围", `{}`, "read:org, repo", true},
+		{"经典 token 没有 repo 范围", `{}`, "read:org, gist", false},
+		{"无法判断时不报错", `{}`, "", true},
+	}
+	for _, tt := range tests {
+		destBody, destScopes, destRequested = tt.body, tt.scopes, false
+		err := validateToken(cfg, repo)
+		if !destRequested {
+			t.Errorf("%s: 没有检查目标仓库", tt.name)
+		}
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: validateToken = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+		if err != nil && !strings.Contains(err.Error(), "owner/dest") {
+			t.Errorf("%s: 错误中应包含仓库名: %v", tt.name, err)
+		}
+	}
+
+	// 通过 ssh 推送时 git 不使用 token
+	destBody, destRequested = `{"permissions":{"push":false}}`, false
+	setOrigin("git@github.com:owner/dest.git")
+	if err := validateToken(cfg, repo); err != nil || destRequested {
+		t.Errorf("ssh 远程不应检查推送权限: err=%v requested=%v", err, destRequested)
+	}
+
+	setOrigin("https://github.com/owner/dest.git")
+	cfg.Push = false
+	if err := validateToken(cfg, repo); err != nil || destRequested {
+		t.Errorf("未指定 -push 时不应检查推送权限: err=%v requested=%v", err, destRequested)
+	}
+}
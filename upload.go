@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// uploadArtifact 将产物及其 -hash-algo 校验文件 (如 .sha256)通过 HTTP PUT 上传到 baseURL 下
+// baseURL 中的用户信息会作为 Basic 认证使用, token 非空时使用 Bearer 认证
+func uploadArtifact(baseURL, token, fileName string, data []byte) error {
+	checksum := fmt.Sprintf("%s  %s\n", hashHex(data), fileName)
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{fileName, data},
+		{fileName + "." + hashAlgo, []byte(checksum)},
+	}
+	for _, f := range files {
+		if err := putFile(baseURL, token, f.name, f.data); err != nil {
+			return fmt.Errorf(tr("上传 %s 失败: %w"), f.name, err)
+		}
+		log.Printf(tr("已上传 %s (%s)"), f.name, formatSize(int64(len(f.data))))
+	}
+	return nil
+}
+
+func putFile(baseURL, token, name string, data []byte) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return err
+	}
+	user := u.User
+	u.User = nil
+	u.Path = path.Join(u.Path, name)
+
+	req, err := newRequest(http.MethodPut, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if user != nil {
+		pass, _ := user.Password()
+		req.SetBasicAuth(user.Username(), pass)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf(tr("服务器返回 %s: %s"), resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
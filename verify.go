@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+)
+
+// runVerify 按元数据中记录的版本重新下载并生成后端和前端文件, 与已提交的文件比较哈希, 不做任何修改
+// 前端归档的时间、属主和权限均取自 dist.zip (见 normalizeTarHeader), 同样可以逐字节复现;
+// 目标目录中没有前端元数据时只校验后端
+func runVerify(cfg *Config, destDir string) int {
+	tag := committedTag(destDir, "sub-store")
+	if tag == "" {
+		log.Printf(tr("%s 中没有后端元数据, 无法确定已提交的版本"), destDir)
+		return exitError
+	}
+	log.Println(tr("已提交的后端版本:"), tag)
+
+	release, err := releaseByTag(cfg, "sub-store-org/Sub-Store", tag)
+	if err != nil {
+		log.Printf(tr("获取后端 release %s 失败: %v"), tag, err)
+		return exitCode(err)
+	}
+	assets, err := backendAssets(cfg, release)
+	if err != nil {
+		log.Println(err)
+		return exitCode(err)
+	}
+	a, err := buildBackend(cfg, destDir, release, assets)
+	if err != nil {
+		log.Println(err)
+		return exitCode(err)
+	}
+	consistent := verifyArtifact(a)
+
+	if tag := committedTag(destDir, "sub-store-frontend"); tag != "" {
+		log.Println(tr("已提交的前端版本:"), tag)
+		release, err := releaseByTag(cfg, "sub-store-org/Sub-Store-Front-End", tag)
+		if err != nil {
+			log.Printf(tr("获取前端 release %s 失败: %v"), tag, err)
+			return exitCode(err)
+		}
+		asset := findAssetBy(release, cfg.MatchField, "dist.zip")
+		if asset == nil {
+			err := withKind(ErrAssetNotFound, errors.New(tr("未找到 dist.zip")))
+			log.Println(err)
+			return exitCode(err)
+		}
+		a, err := buildFrontend(cfg, destDir, release, asset)
+		if err != nil {
+			log.Println(err)
+			return exitCode(err)
+		}
+		consistent = verifyArtifact(a) && consistent
+	}
+	if !consistent {
+		return exitVerifyMismatch
+	}
+	return exitOK
+}
+
+// verifyArtifact 逐个输出 a 中文件与目标目录的比较结果, 全部一致时返回 true
+func verifyArtifact(a *artifact) bool {
+	changed := a.changedFiles()
+	for _, f := range a.files {
+		switch {
+		case !fileExists(f.path):
+			log.Printf(tr("[缺失] %s"), f.path)
+		case containsPath(changed, f.path):
+			log.Printf(tr("[不一致] %s"), f.path)
+		default:
+			log.Printf(tr("[一致] %s"), f.path)
+		}
+	}
+	if len(changed) > 0 {
+		log.Printf(tr("%d 个已提交文件与上游 %s 不一致"), len(changed), a.tag)
+		return false
+	}
+	log.Printf(tr("已提交文件与上游 %s 一致"), a.tag)
+	return true
+}
+
+// fileExists 判断 path 是否存在
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// errVerifyFailed 表示 -verify-command 未通过, 本次提交已回滚
+var errVerifyFailed = withKind(ErrGit, message("提交后校验未通过, 已回滚提交"))
+
+// runVerifyCommand 在 dir 中通过 shell 执行 command, 以环境变量传入组件名和版本
+// 命令以非零状态退出时返回错误, 错误中附带命令输出
+func runVerifyCommand(command, dir, component, tag string) error {
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", command)
+	} else {
+		c = exec.Command("sh", "-c", command)
+	}
+	c.Dir = dir
+	c.Env = append(os.Environ(), "UPDATE_SUB_STORE_COMPONENT="+component, "UPDATE_SUB_STORE_TAG="+tag)
+	out, err := c.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf(tr("%s 失败: %v\n输出: %s"), command, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// verifyCommit 在推送前执行 -verify-command 检查刚提交的文件, 未通过时以 git reset --keep 回滚到 prev
+// --keep 只恢复本次提交改动的文件, 不影响工作区中其他未提交的改动; prev 为空表示这是仓库的第一个提交, 无法回滚
+func verifyCommit(cfg *Config, gitDir, prev, component, tag string) error {
+	log.Printf(tr("执行提交后校验: %s"), cfg.VerifyCommand)
+	verr := runVerifyCommand(cfg.VerifyCommand, gitDir, component, tag)
+	if verr == nil {
+		log.Println(tr("提交后校验通过"))
+		return nil
+	}
+	if prev == "" {
+		return fmt.Errorf(tr("%w: 仓库没有之前的提交, 需手动处理: %w"), ErrGit, verr)
+	}
+	if _, err := runGit(gitDir, "git reset", "reset", "--keep", prev); err != nil {
+		return fmt.Errorf(tr("提交后校验未通过, 且回滚失败, 需手动处理: %w"), errors.Join(verr, err))
+	}
+	log.Printf(tr("已回滚 %s %s 的提交, 仓库恢复到 %s"), component, tag, prev[:min(len(prev), 12)])
+	return fmt.Errorf("%w: %w", errVerifyFailed, verr)
+}
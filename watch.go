@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// watchDelay 返回下一次检查前的等待时间: interval 加上 [0, jitter) 内的随机值
+func watchDelay(interval, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	return interval + rand.N(jitter)
+}
+
+// watchStats 记录守护模式的运行统计, 同时供 -metrics-addr 读取
+type watchStats struct {
+	mu                  sync.Mutex
+	consecutiveFailures int       // 连续失败的检查次数, 成功一次后归零
+	failures            int       // 累计失败次数
+	updates             int       // 累计发现并应用更新的检查次数
+	lastSuccess         time.Time // 最近一次成功检查的时间
+	proxy               string    // 当前使用的代理, 直连时为空
+}
+
+// record 记录一次检查的结果, 返回当前连续失败次数
+func (s *watchStats) record(updated bool, err error) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.consecutiveFailures++
+		s.failures++
+		return s.consecutiveFailures
+	}
+	s.consecutiveFailures = 0
+	s.lastSuccess = time.Now()
+	if updated {
+		s.updates++
+	}
+	return 0
+}
+
+// runWatch 以守护模式循环检查更新, 单次失败只记录日志, 等待期间收到中断信号时正常退出
+// 检查过程中被中断时由 handleInterrupts 清理临时文件后退出
+// 连续失败达到 -watch-max-failures 次 (大于 0 时) 后放弃并返回最后一次错误对应的退出码
+func runWatch(cfg *Config, st *State, destDir, gitDir, proxy string) int {
+	stats := &watchStats{proxy: proxy}
+	if cfg.MetricsAddr != "" {
+		srv := serveMetrics(cfg.MetricsAddr, stats, destDir)
+		defer srv.Close()
+	}
+	log.Printf(tr("守护模式: 每 %s 检查一次更新 (随机抖动 %s)"), cfg.Watch, cfg.WatchJitter)
+	for {
+		startReport(cfg, proxy)
+		updated, err := runOnce(cfg, st, destDir, gitDir)
+		writeReport(cfg, err)
+		if failures := stats.record(updated && !cfg.DryRun, err); err != nil {
+			log.Println(err)
+			log.Printf(tr("本次检查失败, 已连续失败 %d 次"), failures)
+			if cfg.WatchMaxFailures > 0 && failures >= cfg.WatchMaxFailures {
+				log.Printf(tr("连续失败达到 -watch-max-failures %d 次, 守护模式退出"), cfg.WatchMaxFailures)
+				return exitCode(err)
+			}
+		}
+
+		wait := watchDelay(cfg.Watch, cfg.WatchJitter)
+		log.Printf(tr("下次检查将在 %s 后进行"), wait.Round(time.Second))
+		watchWaiting.Store(true)
+		select {
+		case <-interrupted:
+			log.Println(tr("收到退出信号, 守护模式结束"))
+			return exitOK
+		case <-time.After(wait):
+		}
+		watchWaiting.Store(false)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isBareRepo 判断 dir 是否为裸仓库, 裸仓库没有工作区, 不能直接 git add
+func isBareRepo(dir string) bool {
+	out, err := runGit(dir, "git rev-parse", "rev-parse", "--is-bare-repository")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// treePaths 计算文件在裸仓库中的提交路径: 相对 -dest 上级目录的路径, 与非裸仓库的默认布局一致
+func treePaths(cfg *Config, paths []string) ([]string, error) {
+	base := filepath.Dir(cfg.DestDir)
+	rels := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if !isWithin(base, p) {
+			return nil, fmt.Errorf(tr("%s 不在 %s 之内, 无法确定在裸仓库中的提交路径"), p, base)
+		}
+		rel, _ := filepath.Rel(base, p)
+		rels = append(rels, rel)
+	}
+	return rels, nil
+}
+
+// withWorktree 以提交所在目录和各文件相对该目录的路径调用 fn
+// 非裸仓库直接使用 gitDir; 裸仓库先将 paths 复制到 -worktree 指定的工作树 (不存在时创建),
+// 未指定 -worktree 时创建临时工作树, fn 返回后删除
+func withWorktree(cfg *Config, gitDir string, paths []string, fn func(dir string, rels []string) error) error {
+	if !isBareRepo(gitDir) {
+		return fn(gitDir, relPaths(gitDir, paths...))
+	}
+	rels, err := treePaths(cfg, paths)
+	if err != nil {
+		return err
+	}
+	wt := cfg.Worktree
+	if wt == "" {
+		if wt, err = os.MkdirTemp("", "update-sub-store-worktree-"); err != nil {
+			return fmt.Errorf(tr("创建临时工作树失败: %w"), err)
+		}
+		trackTemp(wt)
+		defer func() {
+			if _, err := runGit(gitDir, "git worktree remove", "worktree", "remove", "--force", wt); err != nil {
+				log.Printf(tr("警告: 删除临时工作树 %s 失败: %v"), wt, err)
+				os.RemoveAll(wt)
+				runGit(gitDir, "git worktree prune", "worktree", "prune")
+			}
+			untrackTemp(wt)
+		}()
+	}
+	if err := prepareWorktree(cfg, gitDir, wt); err != nil {
+		return err
+	}
+	for i, p := range paths {
+		if err := copyFile(p, filepath.Join(wt, rels[i])); err != nil {
+			return fmt.Errorf(tr("复制 %s 到工作树失败: %w"), p, err)
+		}
+	}
+	return fn(wt, rels)
+}
+
+// prepareWorktree 确保 wt 是 gitDir 检出 -branch 的工作树, 且与分支最新提交一致
+// 已存在的工作树会丢弃其中的改动, 因此 -worktree 目录应专供本工具使用
+func prepareWorktree(cfg *Config, gitDir, wt string) error {
+	if _, err := os.Stat(filepath.Join(wt, ".git")); err == nil {
+		_, err := runGit(wt, "git checkout", "checkout", "--force", cfg.Branch)
+		return err
+	}
+	if _, err := runGit(gitDir, "git worktree add", "worktree", "add", wt, cfg.Branch); err != nil {
+		return err
+	}
+	if cfg.Verbose {
+		log.Printf(tr("已在 %s 创建 %s 分支的工作树"), wt, cfg.Branch)
+	}
+	return nil
+}
+
+// copyFile 将 src 复制到 dst, 沿用 src 的权限, 按需创建上级目录
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, info.Mode().Perm())
+}